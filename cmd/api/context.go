@@ -9,9 +9,18 @@ import (
 
 type contextKey string
 
-const userContextKey = contextKey("user")
+const (
+	userContextKey      = contextKey("user")
+	requestIDContextKey = contextKey("request_id")
+	// accessLogContextKey holds the *accessLogEntry of the request, which
+	// outer middleware can read once the handlers are done.
+	accessLogContextKey = contextKey("access_log")
+)
 
 func (app *application) contextSetUser(r *http.Request, user *data.User) *http.Request {
+	if entry, ok := r.Context().Value(accessLogContextKey).(*accessLogEntry); ok {
+		entry.user = user
+	}
 	ctx := context.WithValue(r.Context(), userContextKey, user)
 	return r.WithContext(ctx)
 }
@@ -23,3 +32,20 @@ func (app *application) contextGetUser(r *http.Request) *data.User {
 	}
 	return user
 }
+
+func (app *application) contextSetRequestID(r *http.Request, id string) *http.Request {
+	ctx := context.WithValue(r.Context(), requestIDContextKey, id)
+	return r.WithContext(ctx)
+}
+
+// contextGetRequestID() returns the ID assigned by the requestID()
+// middleware, or "" outside of it.
+func (app *application) contextGetRequestID(r *http.Request) string {
+	id, _ := r.Context().Value(requestIDContextKey).(string)
+	return id
+}
+
+func (app *application) contextSetAccessLogEntry(r *http.Request, entry *accessLogEntry) *http.Request {
+	ctx := context.WithValue(r.Context(), accessLogContextKey, entry)
+	return r.WithContext(ctx)
+}
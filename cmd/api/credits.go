@@ -0,0 +1,126 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/arian-nj/site/back/internal/data"
+	"github.com/arian-nj/site/back/internal/validator"
+)
+
+func (app *application) listMovieCreditsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := readParamId(r)
+	if err != nil {
+		app.CustomErrResponse(w, r, http.StatusNotFound, err)
+		return
+	}
+
+	_, err = app.models.Movie.Get(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			app.notFoundResponse(w, r)
+		} else {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	credits, err := app.models.Credits.GetAllForMovie(r.Context(), id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = writeJSON(w, http.StatusOK, envelope{"credits": credits})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) createMovieCreditHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := readParamId(r)
+	if err != nil {
+		app.CustomErrResponse(w, r, http.StatusNotFound, err)
+		return
+	}
+
+	_, err = app.models.Movie.Get(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			app.notFoundResponse(w, r)
+		} else {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		PersonID  int64  `json:"person_id"`
+		Role      string `json:"role"`
+		Character string `json:"character"`
+	}
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	credit := &data.Credit{
+		MovieID:   id,
+		PersonID:  input.PersonID,
+		Role:      input.Role,
+		Character: input.Character,
+	}
+	v := validator.New()
+	if data.ValidateCredit(v, credit); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Credits.Insert(r.Context(), credit)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, data.ErrDuplicateCredit):
+			v.AddError("person_id", "this person is already credited in this role")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = writeJSON(w, http.StatusCreated, envelope{"credit": credit})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteMovieCreditHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := readParamId(r)
+	if err != nil {
+		app.CustomErrResponse(w, r, http.StatusNotFound, err)
+		return
+	}
+	creditID, err := readIntParam(r, "credit_id")
+	if err != nil {
+		app.CustomErrResponse(w, r, http.StatusNotFound, err)
+		return
+	}
+
+	err = app.models.Credits.Delete(r.Context(), id, creditID)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			app.notFoundResponse(w, r)
+		} else {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = writeJSON(w, http.StatusOK, envelope{"message": "credit successfully deleted"})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
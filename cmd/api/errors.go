@@ -1,57 +1,177 @@
 package main
 
 import (
-	"errors"
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"sort"
+	"strings"
 )
 
-func (app *application) CustomErrResponse(w http.ResponseWriter, status int, err error) {
-	writeJSON(w, status, envelope{"error": err.Error()})
+// Error codes are stable, machine-readable identifiers for each kind of
+// error response. They are reported as the code member of problem+json
+// bodies and form the last segment of the problem type URI.
+const (
+	codeBadRequest             = "bad_request"
+	codeFailedValidation       = "failed_validation"
+	codeNotFound               = "not_found"
+	codeMethodNotAllowed       = "method_not_allowed"
+	codeServerError            = "server_error"
+	codeEditConflict           = "edit_conflict"
+	codeUnsupportedMediaType   = "unsupported_media_type"
+	codePreconditionFailed     = "precondition_failed"
+	codeRateLimited            = "rate_limited"
+	codeInvalidCredentials     = "invalid_credentials"
+	codeInvalidToken           = "invalid_authentication_token"
+	codeAuthenticationRequired = "authentication_required"
+	codeInactiveAccount        = "inactive_account"
+	codeNotPermitted           = "not_permitted"
+)
+
+const problemTypePrefix = "urn:problem-type:"
+
+// problem is an RFC 7807 problem details object.
+type problem struct {
+	Type          string         `json:"type"`
+	Title         string         `json:"title"`
+	Status        int            `json:"status"`
+	Detail        string         `json:"detail,omitempty"`
+	Instance      string         `json:"instance,omitempty"`
+	Code          string         `json:"code"`
+	RequestID     string         `json:"request_id,omitempty"`
+	InvalidParams []invalidParam `json:"invalid_params,omitempty"`
+}
+
+type invalidParam struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// wantsProblem() reports whether the error for r should be written as
+// application/problem+json, either because it was enabled for every
+// response or because the client asked for it.
+func (app *application) wantsProblem(r *http.Request) bool {
+	return app.config.problemJSON || strings.Contains(r.Header.Get("Accept"), "application/problem+json")
+}
+
+// errorResponse() writes an error in the format the client negotiated.
+// Legacy clients get {"error": detail}, or the validation map when
+// invalid is set. Both formats carry the request ID when there is one.
+func (app *application) errorResponse(w http.ResponseWriter, r *http.Request, status int, code, detail string, invalid map[string]string) {
+	requestID := app.contextGetRequestID(r)
+	if !app.wantsProblem(r) {
+		env := envelope{"error": detail}
+		if invalid != nil {
+			env["error"] = invalid
+		}
+		if requestID != "" {
+			env["request_id"] = requestID
+		}
+		writeJSON(w, status, env)
+		return
+	}
+
+	p := problem{
+		Type:      problemTypePrefix + code,
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Instance:  r.URL.RequestURI(),
+		Code:      code,
+		RequestID: requestID,
+	}
+	for name, reason := range invalid {
+		p.InvalidParams = append(p.InvalidParams, invalidParam{Name: name, Reason: reason})
+	}
+	sort.Slice(p.InvalidParams, func(i, j int) bool {
+		return p.InvalidParams[i].Name < p.InvalidParams[j].Name
+	})
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(p)
 }
 
-func (app *application) failedValidationResponse(w http.ResponseWriter, errors map[string]string) {
-	writeJSON(w, http.StatusUnprocessableEntity, envelope{"error": errors})
+// statusCode() derives an error code from an HTTP status for responses
+// that don't go through a dedicated helper.
+func statusCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return codeBadRequest
+	case http.StatusNotFound:
+		return codeNotFound
+	case http.StatusInternalServerError:
+		return codeServerError
+	default:
+		return strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_")
+	}
+}
+
+func (app *application) CustomErrResponse(w http.ResponseWriter, r *http.Request, status int, err error) {
+	app.errorResponse(w, r, status, statusCode(status), err.Error(), nil)
+}
+
+func (app *application) failedValidationResponse(w http.ResponseWriter, r *http.Request, errors map[string]string) {
+	app.errorResponse(w, r, http.StatusUnprocessableEntity, codeFailedValidation,
+		"one or more parameters failed validation", errors)
 }
 
 func (app *application) notFoundResponse(w http.ResponseWriter, r *http.Request) {
-	app.CustomErrResponse(w, http.StatusNotFound, errors.New("not found"))
+	app.errorResponse(w, r, http.StatusNotFound, codeNotFound, "not found", nil)
 }
 func (app *application) methodNotAllowed(w http.ResponseWriter, r *http.Request) {
-	app.CustomErrResponse(w, http.StatusNotFound, errors.New("method not allowed"))
+	app.errorResponse(w, r, http.StatusMethodNotAllowed, codeMethodNotAllowed, "method not allowed", nil)
 }
-func (app *application) badRequestResponse(w http.ResponseWriter, err error) {
-	app.CustomErrResponse(w, http.StatusBadRequest, err)
+func (app *application) badRequestResponse(w http.ResponseWriter, r *http.Request, err error) {
+	app.errorResponse(w, r, http.StatusBadRequest, codeBadRequest, err.Error(), nil)
 }
 
 func (app *application) serverErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
-	app.CustomErrResponse(w, http.StatusInternalServerError, errors.New("internal server error"))
-	app.logger.PrintError(err, nil)
+	app.errorResponse(w, r, http.StatusInternalServerError, codeServerError, "internal server error", nil)
+	app.logger.PrintError(err, map[string]string{
+		"request_id": app.contextGetRequestID(r),
+		"method":     r.Method,
+		"url":        r.URL.RequestURI(),
+	})
+}
+
+func (app *application) editConflictResponse(w http.ResponseWriter, r *http.Request) {
+	app.errorResponse(w, r, http.StatusConflict, codeEditConflict, "unable to update the record due to an edit conflict, please try again", nil)
+}
+
+func (app *application) unsupportedMediaTypeResponse(w http.ResponseWriter, r *http.Request, contentType string) {
+	app.errorResponse(w, r, http.StatusUnsupportedMediaType, codeUnsupportedMediaType, fmt.Sprintf("unsupported content type %q", contentType), nil)
 }
 
-func (app *application) editConflictResponse(w http.ResponseWriter) {
-	app.CustomErrResponse(w, http.StatusConflict, errors.New("unable to update the record due to an edit conflict, please try again"))
+func (app *application) preconditionFailedResponse(w http.ResponseWriter, r *http.Request) {
+	app.errorResponse(w, r, http.StatusPreconditionFailed, codePreconditionFailed, "the movie has been modified since the version named in If-Match", nil)
 }
 
-func (app *application) toManyRequestsResponse(w http.ResponseWriter) {
-	app.CustomErrResponse(w, http.StatusConflict, errors.New("rate limit exceeded"))
+func (app *application) toManyRequestsResponse(w http.ResponseWriter, r *http.Request) {
+	app.errorResponse(w, r, http.StatusTooManyRequests, codeRateLimited, "rate limit exceeded", nil)
 }
 func (app *application) invalidcredentialsResponse(w http.ResponseWriter, r *http.Request) {
 	message := "invalid authentication credentials"
-	app.CustomErrResponse(w, http.StatusUnauthorized, errors.New(message))
+	app.errorResponse(w, r, http.StatusUnauthorized, codeInvalidCredentials, message, nil)
 }
 
 func (app *application) invalidAuthenticationTokenResponse(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("WWW-Authenticate", "Bearer")
 	message := "invalid or missing authentication token"
-	app.CustomErrResponse(w, http.StatusUnauthorized, errors.New(message))
+	app.errorResponse(w, r, http.StatusUnauthorized, codeInvalidToken, message, nil)
 }
 
 func (app *application) authenticationRequiredResponse(w http.ResponseWriter, r *http.Request) {
 	message := "you must be authenticated to access this resource"
-	app.CustomErrResponse(w, http.StatusUnauthorized, errors.New(message))
+	app.errorResponse(w, r, http.StatusUnauthorized, codeAuthenticationRequired, message, nil)
 }
 func (app *application) inactiveAccountResponse(w http.ResponseWriter, r *http.Request) {
 	message := "your user account must be activated to access this resource"
-	app.CustomErrResponse(w, http.StatusForbidden, errors.New(message))
+	app.errorResponse(w, r, http.StatusForbidden, codeInactiveAccount, message, nil)
+
+}
 
+func (app *application) notPermittedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "your user account doesn't have the necessary permissions to access this resource"
+	app.errorResponse(w, r, http.StatusForbidden, codeNotPermitted, message, nil)
 }
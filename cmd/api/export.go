@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/arian-nj/site/back/internal/data"
+	"github.com/arian-nj/site/back/internal/validator"
+)
+
+// movieExporter writes movies one at a time in a single output format.
+type movieExporter interface {
+	contentType() string
+	extension() string
+	begin() error
+	write(movie *data.Movie) error
+	end() error
+}
+
+type csvExporter struct{ w *csv.Writer }
+
+func (e *csvExporter) contentType() string { return "text/csv; charset=utf-8" }
+func (e *csvExporter) extension() string   { return "csv" }
+
+func (e *csvExporter) begin() error {
+	return e.w.Write([]string{"id", "title", "year", "runtime", "genres", "version"})
+}
+
+func (e *csvExporter) write(movie *data.Movie) error {
+	return e.w.Write([]string{
+		strconv.FormatInt(movie.ID, 10),
+		movie.Title,
+		strconv.Itoa(int(movie.Year)),
+		strconv.Itoa(int(movie.Runtime)),
+		strings.Join(movie.Genres, ","),
+		strconv.Itoa(int(movie.Version)),
+	})
+}
+
+func (e *csvExporter) end() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+type ndjsonExporter struct{ enc *json.Encoder }
+
+func (e *ndjsonExporter) contentType() string           { return "application/x-ndjson" }
+func (e *ndjsonExporter) extension() string             { return "ndjson" }
+func (e *ndjsonExporter) begin() error                  { return nil }
+func (e *ndjsonExporter) write(movie *data.Movie) error { return e.enc.Encode(movie) }
+func (e *ndjsonExporter) end() error                    { return nil }
+
+type jsonExporter struct {
+	w     io.Writer
+	count int
+}
+
+func (e *jsonExporter) contentType() string { return "application/json; charset=utf-8" }
+func (e *jsonExporter) extension() string   { return "json" }
+
+func (e *jsonExporter) begin() error {
+	_, err := io.WriteString(e.w, "[")
+	return err
+}
+
+func (e *jsonExporter) write(movie *data.Movie) error {
+	js, err := json.Marshal(movie)
+	if err != nil {
+		return err
+	}
+	if e.count > 0 {
+		js = append([]byte(","), js...)
+	}
+	e.count++
+	_, err = e.w.Write(js)
+	return err
+}
+
+func (e *jsonExporter) end() error {
+	_, err := io.WriteString(e.w, "]\n")
+	return err
+}
+
+// exportFormat() picks the output format from the format query parameter,
+// falling back to the Accept header and then to JSON.
+func exportFormat(r *http.Request, format string) string {
+	if format != "" {
+		return format
+	}
+	accept := r.Header.Get("Accept")
+	switch {
+	case strings.Contains(accept, "text/csv"):
+		return "csv"
+	case strings.Contains(accept, "application/x-ndjson"):
+		return "ndjson"
+	default:
+		return "json"
+	}
+}
+
+func (app *application) exportMovieHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	qs := r.URL.Query()
+
+	filter := app.readMovieFilter(qs, v)
+	format := exportFormat(r, app.readString(qs, "format", ""))
+	v.Check(validator.In(format, "csv", "ndjson", "json"), "format", "must be csv, ndjson or json")
+	data.ValidateMovieFilter(v, filter, data.Filters{})
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	var exporter movieExporter
+	switch format {
+	case "csv":
+		exporter = &csvExporter{w: csv.NewWriter(w)}
+	case "ndjson":
+		exporter = &ndjsonExporter{enc: json.NewEncoder(w)}
+	default:
+		exporter = &jsonExporter{w: w}
+	}
+
+	// A full export can outlast the server's WriteTimeout.
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	// The response is only committed once the first row arrives, so a failing
+	// query can still be reported as a normal error response.
+	started := false
+	start := func() error {
+		started = true
+		w.Header().Set("Content-Type", exporter.contentType())
+		w.Header().Set("Content-Disposition", `attachment; filename="movies.`+exporter.extension()+`"`)
+		w.WriteHeader(http.StatusOK)
+		return exporter.begin()
+	}
+
+	err := app.models.Movie.Each(r.Context(), filter, func(movie *data.Movie) error {
+		if !started {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		return exporter.write(movie)
+	})
+	if err != nil {
+		if !started {
+			app.serverErrorResponse(w, r, err)
+		} else {
+			app.logger.PrintError(err, map[string]string{"export": format})
+		}
+		return
+	}
+	if !started {
+		if err := start(); err != nil {
+			app.logger.PrintError(err, nil)
+			return
+		}
+	}
+	if err := exporter.end(); err != nil {
+		app.logger.PrintError(err, nil)
+	}
+}
@@ -2,8 +2,10 @@ package main
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/arian-nj/site/back/internal/data"
 	"github.com/arian-nj/site/back/internal/validator"
@@ -31,7 +33,7 @@ func (app *application) createMovieHandler(w http.ResponseWriter, r *http.Reques
 
 	err := app.readJSON(w, r, &input)
 	if err != nil {
-		app.badRequestResponse(w, err)
+		app.badRequestResponse(w, r, err)
 		return
 	}
 
@@ -45,11 +47,11 @@ func (app *application) createMovieHandler(w http.ResponseWriter, r *http.Reques
 
 	data.ValidateMovie(v, movie)
 	if !v.Valid() {
-		app.failedValidationResponse(w, v.Errors)
+		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	err = app.models.Movie.Insert(movie)
+	err = app.models.Movie.Insert(r.Context(), movie)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -63,11 +65,11 @@ func (app *application) createMovieHandler(w http.ResponseWriter, r *http.Reques
 func (app *application) getMovieHandler(w http.ResponseWriter, r *http.Request) {
 	id, err := readParamId(r)
 	if err != nil {
-		app.CustomErrResponse(w, http.StatusNotFound, err)
+		app.CustomErrResponse(w, r, http.StatusNotFound, err)
 		return
 	}
 
-	movie, err := app.models.Movie.Get(id)
+	movie, err := app.models.Movie.Get(r.Context(), id)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -78,6 +80,13 @@ func (app *application) getMovieHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	etag := movieETag(movie)
+	w.Header().Set("ETag", etag)
+	if match := r.Header.Get("If-None-Match"); match != "" && etagMatches(match, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
 	err = writeJSON(w, http.StatusOK, envelope{"movie": movie})
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -85,23 +94,27 @@ func (app *application) getMovieHandler(w http.ResponseWriter, r *http.Request)
 }
 
 func readParamId(r *http.Request) (int64, error) {
-	strId := r.PathValue("id")
-	id, err := strconv.ParseInt(strId, 10, 64)
+	return readIntParam(r, "id")
+}
+
+func readIntParam(r *http.Request, name string) (int64, error) {
+	str := r.PathValue(name)
+	i, err := strconv.ParseInt(str, 10, 64)
 	if err != nil {
-		return 0, errors.New("invalid id parameter")
+		return 0, fmt.Errorf("invalid %s parameter", name)
 	}
-	return id, err
+	return i, err
 }
 
 func (app *application) updateMovieHandler(w http.ResponseWriter, r *http.Request) {
 
 	id, err := readParamId(r)
 	if err != nil {
-		app.CustomErrResponse(w, http.StatusBadGateway, err)
+		app.notFoundResponse(w, r)
 		return
 	}
 
-	movie, err := app.models.Movie.Get(id)
+	movie, err := app.models.Movie.Get(r.Context(), id)
 	if err != nil {
 		if errors.Is(err, data.ErrRecordNotFound) {
 			app.notFoundResponse(w, r)
@@ -111,6 +124,12 @@ func (app *application) updateMovieHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch != "" && !versionMatches(ifMatch, movie.Version) {
+		app.preconditionFailedResponse(w, r)
+		return
+	}
+
 	var input struct {
 		Title   *string       `json:"title"`
 		Year    *int32        `json:"year"`
@@ -120,7 +139,7 @@ func (app *application) updateMovieHandler(w http.ResponseWriter, r *http.Reques
 
 	err = app.readJSON(w, r, &input)
 	if err != nil {
-		app.badRequestResponse(w, err)
+		app.badRequestResponse(w, r, err)
 		return
 	}
 
@@ -141,20 +160,24 @@ func (app *application) updateMovieHandler(w http.ResponseWriter, r *http.Reques
 
 	data.ValidateMovie(v, movie)
 	if !v.Valid() {
-		app.failedValidationResponse(w, v.Errors)
+		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	err = app.models.Movie.Update(movie)
+	err = app.models.Movie.Update(r.Context(), movie, app.contextGetUser(r).ID)
 	if err != nil {
-		if errors.Is(err, data.ErrEditConflict) {
-			app.editConflictResponse(w)
-		} else {
+		switch {
+		case errors.Is(err, data.ErrEditConflict) && ifMatch != "":
+			app.preconditionFailedResponse(w, r)
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
 			app.serverErrorResponse(w, r, err)
 		}
 		return
 	}
 
+	w.Header().Set("ETag", movieETag(movie))
 	err = writeJSON(w, http.StatusOK, envelope{"movie": movie})
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -164,20 +187,44 @@ func (app *application) updateMovieHandler(w http.ResponseWriter, r *http.Reques
 func (app *application) deleteMovieHandler(w http.ResponseWriter, r *http.Request) {
 	id, err := readParamId(r)
 	if err != nil {
-		app.CustomErrResponse(w, http.StatusNotFound, err)
+		app.CustomErrResponse(w, r, http.StatusNotFound, err)
 		return
 	}
-	err = app.models.Movie.Delete(id)
+
+	// The delete is made conditional on the version the If-Match header was
+	// checked against, so an update landing in between fails it too.
+	var version int32
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" {
+		movie, err := app.models.Movie.Get(r.Context(), id)
+		if err != nil {
+			if errors.Is(err, data.ErrRecordNotFound) {
+				app.notFoundResponse(w, r)
+			} else {
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+		if !versionMatches(ifMatch, movie.Version) {
+			app.preconditionFailedResponse(w, r)
+			return
+		}
+		version = movie.Version
+	}
+
+	err = app.models.Movie.Delete(r.Context(), id, version)
 	if err != nil {
-		if errors.Is(err, data.ErrRecordNotFound) {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
 			app.notFoundResponse(w, r)
-		} else {
+		case errors.Is(err, data.ErrEditConflict):
+			app.preconditionFailedResponse(w, r)
+		default:
 			app.serverErrorResponse(w, r, err)
 		}
 		return
 	}
 
-	err = writeJSON(w, http.StatusOK, envelope{"message": "movie successfully deleted"})
+	err = writeJSON(w, http.StatusOK, envelope{"message": "movie moved to trash"})
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -186,36 +233,82 @@ func (app *application) deleteMovieHandler(w http.ResponseWriter, r *http.Reques
 
 func (app *application) listMovieHandler(w http.ResponseWriter, r *http.Request) {
 	var input struct {
-		Title  string
-		Genres []string
+		data.MovieFilter
 		data.Filters
+		Facets []string
 	}
 	v := validator.New()
 	qs := r.URL.Query()
 
-	input.Title = app.readString(qs, "title", "")
-	input.Genres = app.readCSV(qs, "genres", []string{})
+	input.MovieFilter = app.readMovieFilter(qs, v)
+	input.Facets = app.readCSV(qs, "facets", []string{})
 	input.Filters.Page = app.readInt(qs, "page", 1, v)
 	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
 	input.Filters.Sort = app.readString(qs, "sort", "id")
+	input.Filters.Cursor = app.readString(qs, "cursor", "")
 
-	input.Filters.SortSafelist = []string{"id", "title", "year", "runtime",
-		"-id", "-title", "-year", "-runtime"}
+	input.Filters.SortSafelist = []string{"id", "title", "year", "runtime", "average_rating", "rating_count", "relevance",
+		"-id", "-title", "-year", "-runtime", "-average_rating", "-rating_count", "-relevance"}
 
+	data.ValidateMovieFilter(v, input.MovieFilter, input.Filters)
+	data.ValidateFacets(v, input.Facets)
 	data.ValidateFilter(v, input.Filters)
 	if !v.Valid() {
-		app.failedValidationResponse(w, v.Errors)
+		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
-	movies, metadata, err := app.models.Movie.GetAll(input.Title, input.Genres, input.Filters)
+	movies, metadata, err := app.models.Movie.GetAll(r.Context(), input.MovieFilter, input.Filters)
+	if err != nil {
+		if errors.Is(err, data.ErrInvalidCursor) {
+			app.badRequestResponse(w, r, err)
+		} else {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	response := envelope{"metadata": metadata, "movies": movies}
+	if len(input.Facets) > 0 {
+		facets, err := app.models.Movie.Facets(r.Context(), input.MovieFilter, input.Facets)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		response["facets"] = facets
+	}
+
+	err = writeJSON(w, http.StatusOK, response)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
+	}
+
+}
+
+func (app *application) suggestMovieHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	qs := r.URL.Query()
+
+	q := strings.TrimSpace(app.readString(qs, "q", ""))
+	limit := app.readInt(qs, "limit", 10, v)
+	threshold := app.readFloat(qs, "threshold", 0.3, v)
+
+	v.Check(q != "", "q", "must be provided")
+	v.Check(len(q) <= 100, "q", "must not be more than 100 bytes long")
+	v.Check(limit > 0 && limit <= 20, "limit", "must be between 1 and 20")
+	v.Check(threshold >= 0 && threshold <= 1, "threshold", "must be between 0 and 1")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	err = writeJSON(w, http.StatusOK, envelope{"metadata": metadata, "movies": movies})
+	suggestions, err := app.models.Movie.Suggest(r.Context(), q, limit, threshold)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
+		return
 	}
 
+	err = writeJSON(w, http.StatusOK, envelope{"suggestions": suggestions})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
 }
@@ -5,11 +5,14 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/arian-nj/site/back/internal/data"
 	"github.com/arian-nj/site/back/internal/validator"
 )
 
@@ -93,11 +96,140 @@ func (app *application) readInt(qs url.Values, key string, defaultValue int, v *
 	return i
 }
 
+// readInt32() is readInt() for int32 fields, reporting values outside the
+// int32 range instead of letting them wrap.
+func (app *application) readInt32(qs url.Values, key string, defaultValue int32, v *validator.Validator) int32 {
+	s := qs.Get(key)
+	if s == "" {
+		return defaultValue
+	}
+	i, err := strconv.ParseInt(s, 10, 32)
+	if err != nil {
+		if errors.Is(err, strconv.ErrRange) {
+			v.AddError(key, "is out of range")
+		} else {
+			v.AddError(key, "must be an integer value")
+		}
+		return defaultValue
+	}
+	return int32(i)
+}
+
+// movieETag() derives a strong entity tag from a movie's version and its
+// rating summary. Reviews change the rating without bumping the version, so
+// both go into the tag.
+func movieETag(movie *data.Movie) string {
+	return fmt.Sprintf(`"%d-%d-%s"`, movie.Version, movie.RatingCount,
+		strconv.FormatFloat(movie.AverageRating, 'f', 2, 64))
+}
+
+// versionMatches() reports whether an If-Match header names a tag built by
+// movieETag() for version. Only the version part is compared, so a review
+// posted in the meantime doesn't fail a catalog edit.
+func versionMatches(header string, version int32) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if strings.HasPrefix(candidate, "W/") {
+			continue
+		}
+		v, _, _ := strings.Cut(strings.Trim(candidate, `"`), "-")
+		if v == strconv.Itoa(int(version)) {
+			return true
+		}
+	}
+	return false
+}
+
+// etagMatches() reports whether etag appears in an If-None-Match header
+// value, using the weak comparison that header calls for.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// bearerToken() extracts the token from an "Authorization: Bearer <token>"
+// header.
+func bearerToken(r *http.Request) (string, bool) {
+	headerParts := strings.Split(r.Header.Get("Authorization"), " ")
+	if len(headerParts) != 2 || headerParts[0] != "Bearer" {
+		return "", false
+	}
+	return headerParts[1], true
+}
+
+func clientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return ip
+}
+
+func (app *application) readFloat(qs url.Values, key string, defaultValue float64, v *validator.Validator) float64 {
+	s := qs.Get(key)
+	if s == "" {
+		return defaultValue
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		v.AddError(key, "must be a number")
+		return defaultValue
+	}
+	return f
+}
+
+// readTime() accepts an RFC 3339 timestamp or a plain 2006-01-02 date.
+func (app *application) readTime(qs url.Values, key string, v *validator.Validator) time.Time {
+	s := qs.Get(key)
+	if s == "" {
+		return time.Time{}
+	}
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	v.AddError(key, "must be a date (2006-01-02) or RFC 3339 timestamp")
+	return time.Time{}
+}
+
+// readMovieFilter() reads the movie filter parameters shared by the list
+// and export endpoints.
+func (app *application) readMovieFilter(qs url.Values, v *validator.Validator) data.MovieFilter {
+	return data.MovieFilter{
+		Title:         app.readString(qs, "title", ""),
+		Genres:        app.readCSV(qs, "genres", []string{}),
+		PersonID:      int64(app.readInt(qs, "person", 0, v)),
+		Language:      app.readString(qs, "lang", "simple"),
+		GenresAny:     app.readCSV(qs, "genres_any", []string{}),
+		ExcludeGenres: app.readCSV(qs, "-genres", []string{}),
+		YearMin:       app.readInt32(qs, "year_min", 0, v),
+		YearMax:       app.readInt32(qs, "year_max", 0, v),
+		RuntimeMin:    app.readInt32(qs, "runtime_min", 0, v),
+		RuntimeMax:    app.readInt32(qs, "runtime_max", 0, v),
+		CreatedAfter:  app.readTime(qs, "created_after", v),
+		CreatedBefore: app.readTime(qs, "created_before", v),
+	}
+}
+
 func (app *application) background(fn func()) {
 	app.wg.Add(1)
+	app.backgroundTasks.Add(1)
 	// Launch a background goroutine.
 	go func() {
 		defer app.wg.Done()
+		defer app.backgroundTasks.Add(-1)
 		// Recover any panic.
 		defer func() {
 			if err := recover(); err != nil {
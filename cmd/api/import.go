@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/arian-nj/site/back/internal/data"
+	"github.com/arian-nj/site/back/internal/validator"
+)
+
+const (
+	maxImportBytes = 10 << 20
+	maxImportRows  = 10_000
+)
+
+// importRow is the per-row entry of the import report. Exactly one of ID
+// and Errors is set.
+type importRow struct {
+	Row    int               `json:"row"`
+	ID     int64             `json:"id,omitempty"`
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// importMovieHandler() bulk creates movies from a CSV or NDJSON body. By
+// default any invalid row aborts the whole import; with mode=partial the
+// invalid rows are reported and the valid ones are still created, unless
+// there are none.
+func (app *application) importMovieHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	mode := app.readString(r.URL.Query(), "mode", "all")
+	v.Check(validator.In(mode, "all", "partial"), "mode", "must be all or partial")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+
+	var (
+		rows []importRow
+		err  error
+	)
+	movies := map[int]*data.Movie{}
+	switch contentType {
+	case "text/csv":
+		rows, err = parseCSVMovies(r.Body, movies)
+	case "application/x-ndjson":
+		rows, err = parseNDJSONMovies(r.Body, movies)
+	default:
+		app.unsupportedMediaTypeResponse(w, r, contentType)
+		return
+	}
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if len(rows) == 0 {
+		app.badRequestResponse(w, r, errors.New("body must contain at least one movie"))
+		return
+	}
+
+	// Nothing is created when any row fails in mode=all, or when every row
+	// fails in mode=partial.
+	failed := len(rows) - len(movies)
+	if failed > 0 && (mode == "all" || len(movies) == 0) {
+		err = writeJSON(w, http.StatusUnprocessableEntity, envelope{
+			"mode": mode, "created": 0, "failed": failed, "rows": rows,
+		})
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	batch := make([]*data.Movie, 0, len(movies))
+	for i := range rows {
+		if movie, ok := movies[rows[i].Row]; ok {
+			batch = append(batch, movie)
+		}
+	}
+	err = app.models.Movie.InsertBatch(r.Context(), batch)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	for i := range rows {
+		if movie, ok := movies[rows[i].Row]; ok {
+			rows[i].ID = movie.ID
+		}
+	}
+
+	err = writeJSON(w, http.StatusCreated, envelope{
+		"mode": mode, "created": len(batch), "failed": failed, "rows": rows,
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// parseCSVMovies() reads a CSV document with a title,year,runtime,genres
+// header. Runtime is a number of minutes (or "X mins") and genres is a comma
+// separated list inside a single quoted cell. Valid movies are stored in
+// movies keyed by their row number.
+func parseCSVMovies(body io.Reader, movies map[int]*data.Movie) ([]importRow, error) {
+	cr := csv.NewReader(body)
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("body must not be empty")
+		}
+		return nil, fmt.Errorf("invalid CSV header: %v", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"title", "year", "runtime", "genres"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("CSV header must contain a %q column", name)
+		}
+	}
+
+	var rows []importRow
+	for n := 1; ; n++ {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if n > maxImportRows {
+			return nil, fmt.Errorf("body must not contain more than %d movies", maxImportRows)
+		}
+		row := importRow{Row: n}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) || !errors.Is(err, csv.ErrFieldCount) {
+				return nil, fmt.Errorf("invalid CSV on row %d: %v", n, err)
+			}
+			row.Errors = map[string]string{"row": "wrong number of fields"}
+			rows = append(rows, row)
+			continue
+		}
+
+		v := validator.New()
+		movie := &data.Movie{Title: record[columns["title"]]}
+		year, err := strconv.ParseInt(strings.TrimSpace(record[columns["year"]]), 10, 32)
+		v.Check(err == nil, "year", "must be an integer value")
+		movie.Year = int32(year)
+		runtime, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimSpace(record[columns["runtime"]]), " mins"), 10, 32)
+		v.Check(err == nil, "runtime", "must be an integer number of minutes")
+		movie.Runtime = data.Runtime(runtime)
+		movie.Genres = []string{}
+		for _, genre := range strings.Split(record[columns["genres"]], ",") {
+			if genre = strings.TrimSpace(genre); genre != "" {
+				movie.Genres = append(movie.Genres, genre)
+			}
+		}
+
+		rows = append(rows, validateImportedMovie(v, row, movie, movies))
+	}
+	return rows, nil
+}
+
+// parseNDJSONMovies() reads one JSON movie per line, in the same shape
+// accepted by POST /v1/movies. Blank lines are skipped.
+func parseNDJSONMovies(body io.Reader, movies map[int]*data.Movie) ([]importRow, error) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+
+	var rows []importRow
+	for n := 1; scanner.Scan(); n++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if len(rows) >= maxImportRows {
+			return nil, fmt.Errorf("body must not contain more than %d movies", maxImportRows)
+		}
+		row := importRow{Row: n}
+
+		var input struct {
+			Title   string       `json:"title"`
+			Year    int32        `json:"year"`
+			Runtime data.Runtime `json:"runtime"`
+			Genres  []string     `json:"genres"`
+		}
+		dec := json.NewDecoder(bytes.NewReader(line))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&input); err != nil {
+			row.Errors = map[string]string{"row": err.Error()}
+			rows = append(rows, row)
+			continue
+		}
+
+		movie := &data.Movie{
+			Title:   input.Title,
+			Year:    input.Year,
+			Runtime: input.Runtime,
+			Genres:  input.Genres,
+		}
+		rows = append(rows, validateImportedMovie(validator.New(), row, movie, movies))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+func validateImportedMovie(v *validator.Validator, row importRow, movie *data.Movie, movies map[int]*data.Movie) importRow {
+	data.ValidateMovie(v, movie)
+	if !v.Valid() {
+		row.Errors = v.Errors
+		return row
+	}
+	movies[row.Row] = movie
+	return row
+}
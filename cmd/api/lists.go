@@ -0,0 +1,404 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/arian-nj/site/back/internal/data"
+	"github.com/arian-nj/site/back/internal/validator"
+)
+
+// readOwnList() loads the list named by the id path parameter, which may
+// also be the literal "watchlist". Lists owned by someone else are reported
+// as not found.
+func (app *application) readOwnList(w http.ResponseWriter, r *http.Request) (*data.List, bool) {
+	user := app.contextGetUser(r)
+
+	var (
+		list *data.List
+		err  error
+	)
+	if r.PathValue("id") == data.DefaultListName {
+		list, err = app.models.Lists.GetDefault(r.Context(), user.ID)
+	} else {
+		var id int64
+		id, err = readParamId(r)
+		if err != nil {
+			app.CustomErrResponse(w, r, http.StatusNotFound, err)
+			return nil, false
+		}
+		list, err = app.models.Lists.Get(r.Context(), id)
+	}
+	if err == nil && list.UserID != user.ID {
+		err = data.ErrRecordNotFound
+	}
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			app.notFoundResponse(w, r)
+		} else {
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+	return list, true
+}
+
+func (app *application) listListsHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	_, err := app.models.Lists.GetDefault(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	lists, err := app.models.Lists.GetAllForUser(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = writeJSON(w, http.StatusOK, envelope{"lists": lists})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) createListHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name       string `json:"name"`
+		Visibility string `json:"visibility"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if input.Visibility == "" {
+		input.Visibility = data.VisibilityPrivate
+	}
+
+	list := &data.List{
+		UserID:     app.contextGetUser(r).ID,
+		Name:       input.Name,
+		Visibility: input.Visibility,
+	}
+	v := validator.New()
+	if data.ValidateList(v, list); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Lists.Insert(r.Context(), list)
+	if err != nil {
+		if errors.Is(err, data.ErrDuplicateListName) {
+			v.AddError("name", "you already have a list with this name")
+			app.failedValidationResponse(w, r, v.Errors)
+		} else {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = writeJSON(w, http.StatusCreated, envelope{"list": list})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) getListHandler(w http.ResponseWriter, r *http.Request) {
+	list, ok := app.readOwnList(w, r)
+	if !ok {
+		return
+	}
+
+	err := writeJSON(w, http.StatusOK, envelope{"list": list})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateListHandler(w http.ResponseWriter, r *http.Request) {
+	list, ok := app.readOwnList(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Name       *string `json:"name"`
+		Visibility *string `json:"visibility"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if input.Name != nil {
+		v.Check(!list.IsDefault || *input.Name == list.Name, "name", "the watchlist cannot be renamed")
+		list.Name = *input.Name
+	}
+	if input.Visibility != nil {
+		list.Visibility = *input.Visibility
+	}
+	if data.ValidateList(v, list); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Lists.Update(r.Context(), list)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateListName):
+			v.AddError("name", "you already have a list with this name")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = writeJSON(w, http.StatusOK, envelope{"list": list})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteListHandler(w http.ResponseWriter, r *http.Request) {
+	list, ok := app.readOwnList(w, r)
+	if !ok {
+		return
+	}
+	if list.IsDefault {
+		v := validator.New()
+		v.AddError("list", "the watchlist cannot be deleted")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err := app.models.Lists.Delete(r.Context(), list.ID)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			app.notFoundResponse(w, r)
+		} else {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = writeJSON(w, http.StatusOK, envelope{"message": "list successfully deleted"})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listListMoviesHandler(w http.ResponseWriter, r *http.Request) {
+	list, ok := app.readOwnList(w, r)
+	if !ok {
+		return
+	}
+	app.writeListMovies(w, r, list)
+}
+
+// getPublicListHandler() shows a list to anyone if it is public, and to its
+// owner otherwise.
+func (app *application) getPublicListHandler(w http.ResponseWriter, r *http.Request) {
+	list, ok := app.readVisibleList(w, r)
+	if !ok {
+		return
+	}
+
+	err := writeJSON(w, http.StatusOK, envelope{"list": list})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listPublicListMoviesHandler(w http.ResponseWriter, r *http.Request) {
+	list, ok := app.readVisibleList(w, r)
+	if !ok {
+		return
+	}
+	app.writeListMovies(w, r, list)
+}
+
+func (app *application) readVisibleList(w http.ResponseWriter, r *http.Request) (*data.List, bool) {
+	id, err := readParamId(r)
+	if err != nil {
+		app.CustomErrResponse(w, r, http.StatusNotFound, err)
+		return nil, false
+	}
+
+	list, err := app.models.Lists.Get(r.Context(), id)
+	if err == nil && !list.IsPublic() && list.UserID != app.contextGetUser(r).ID {
+		err = data.ErrRecordNotFound
+	}
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			app.notFoundResponse(w, r)
+		} else {
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+	return list, true
+}
+
+func (app *application) writeListMovies(w http.ResponseWriter, r *http.Request, list *data.List) {
+	var filters data.Filters
+	v := validator.New()
+	qs := r.URL.Query()
+	filters.Page = app.readInt(qs, "page", 1, v)
+	filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	filters.Sort = app.readString(qs, "sort", "position")
+	filters.SortSafelist = []string{"position", "added_at", "title", "-position", "-added_at", "-title"}
+
+	data.ValidateFilter(v, filters)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	items, metadata, err := app.models.Lists.GetMovies(r.Context(), list.ID, filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = writeJSON(w, http.StatusOK, envelope{"list": list, "metadata": metadata, "movies": items})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) addListMovieHandler(w http.ResponseWriter, r *http.Request) {
+	list, ok := app.readOwnList(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		MovieID  int64 `json:"movie_id"`
+		Position *int  `json:"position"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(input.MovieID > 0, "movie_id", "must be provided")
+	if input.Position != nil {
+		v.Check(*input.Position > 0, "position", "must be bigger than 0")
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// Adding at a position is one change: the movie must not stay appended
+	// at the end if moving it fails.
+	var item *data.ListItem
+	err = app.models.WithTx(r.Context(), func(tx *data.Models) error {
+		var err error
+		item, err = tx.Lists.AddMovie(r.Context(), list.ID, input.MovieID)
+		if err != nil || input.Position == nil {
+			return err
+		}
+		item, err = tx.Lists.MoveMovie(r.Context(), list.ID, input.MovieID, *input.Position)
+		return err
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, data.ErrDuplicateListItem):
+			v.AddError("movie_id", "this movie is already in the list")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = writeJSON(w, http.StatusCreated, envelope{"movie_id": input.MovieID, "position": item.Position})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) moveListMovieHandler(w http.ResponseWriter, r *http.Request) {
+	list, movieID, ok := app.readOwnListMovie(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Position int `json:"position"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if v.Check(input.Position > 0, "position", "must be bigger than 0"); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	item, err := app.models.Lists.MoveMovie(r.Context(), list.ID, movieID, input.Position)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			app.notFoundResponse(w, r)
+		} else {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = writeJSON(w, http.StatusOK, envelope{"movie_id": movieID, "position": item.Position})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) removeListMovieHandler(w http.ResponseWriter, r *http.Request) {
+	list, movieID, ok := app.readOwnListMovie(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.models.Lists.RemoveMovie(r.Context(), list.ID, movieID)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			app.notFoundResponse(w, r)
+		} else {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = writeJSON(w, http.StatusOK, envelope{"message": "movie successfully removed from list"})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) readOwnListMovie(w http.ResponseWriter, r *http.Request) (*data.List, int64, bool) {
+	list, ok := app.readOwnList(w, r)
+	if !ok {
+		return nil, 0, false
+	}
+	movieID, err := strconv.ParseInt(r.PathValue("movie_id"), 10, 64)
+	if err != nil {
+		app.CustomErrResponse(w, r, http.StatusNotFound, errors.New("invalid movie_id parameter"))
+		return nil, 0, false
+	}
+	return list, movieID, true
+}
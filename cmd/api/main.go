@@ -1,13 +1,19 @@
 package main
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"flag"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/arian-nj/site/back/internal/data"
 	"github.com/arian-nj/site/back/internal/jsonlog"
@@ -24,6 +30,16 @@ func writeJSON(w http.ResponseWriter, status int, data any) error {
 type config struct {
 	port    int
 	env     string
+	storage string
+	db      struct {
+		driver       string
+		dsn          string
+		maxOpenConns int
+		maxIdleConns int
+		maxIdleTime  time.Duration
+		queryTimeout time.Duration
+		migrate      bool
+	}
 	limiter struct {
 		rps     float64
 		burst   int
@@ -39,14 +55,22 @@ type config struct {
 	cors struct {
 		trustedOrigins []string
 	}
+	problemJSON bool
+	trash       struct {
+		retention time.Duration
+		interval  time.Duration
+	}
 }
 
 type application struct {
-	config config
-	logger *jsonlog.Logger
-	models *data.Models
-	mailer mailer.Mailer
-	wg     sync.WaitGroup
+	config          config
+	logger          *jsonlog.Logger
+	db              *sql.DB
+	models          *data.Models
+	mailer          mailer.Mailer
+	instruments     *appMetrics
+	wg              sync.WaitGroup
+	backgroundTasks atomic.Int64
 }
 
 const version = "1.0.0"
@@ -61,6 +85,16 @@ func main() {
 	var cfg config
 	flag.IntVar(&cfg.port, "port", 4000, "Api server port")
 	flag.StringVar(&cfg.env, "env", "development", "Environment (development | staging | production)")
+
+	flag.StringVar(&cfg.storage, "storage", "database", "Storage backend (database|memory; postgres is kept as an alias of database)")
+	flag.StringVar(&cfg.db.driver, "db-driver", envString("DB_DRIVER", data.DriverPostgres), "Database driver (postgres|sqlite)")
+	flag.StringVar(&cfg.db.dsn, "db-dsn", os.Getenv("DB_DSN"), "Database DSN (a file name for sqlite)")
+	flag.IntVar(&cfg.db.maxOpenConns, "db-max-open-conns", envInt("DB_MAX_OPEN_CONNS", 25), "Database max open connections")
+	flag.IntVar(&cfg.db.maxIdleConns, "db-max-idle-conns", envInt("DB_MAX_IDLE_CONNS", 25), "Database max idle connections")
+	flag.DurationVar(&cfg.db.maxIdleTime, "db-max-idle-time", envDuration("DB_MAX_IDLE_TIME", 15*time.Minute), "Database max connection idle time")
+	flag.DurationVar(&cfg.db.queryTimeout, "db-query-timeout", envDuration("DB_QUERY_TIMEOUT", 3*time.Second), "Database per-query timeout (imports, exports and trash purges get a multiple of it)")
+	flag.BoolVar(&cfg.db.migrate, "db-migrate", false, "Apply pending database migrations on startup")
+
 	flag.Float64Var(&cfg.limiter.rps, "limiter-rps", 2, "Rate limiter maximum requests per second")
 	flag.IntVar(&cfg.limiter.burst, "limiter-burst", 4, "Rate limiter maximum burst")
 	flag.BoolVar(&cfg.limiter.enabled, "limiter-enabled", true, "Enable rate limiter")
@@ -70,6 +104,9 @@ func main() {
 	flag.StringVar(&cfg.smtp.username, "smtp-username", os.Getenv("SMTP_USERNAME"), "SMTP username")
 	flag.StringVar(&cfg.smtp.password, "smtp-password", os.Getenv("SMTP_PASSWORD"), "SMTP password")
 	flag.StringVar(&cfg.smtp.sender, "smtp-sender", os.Getenv("SMTP_SENDER"), "SMTP sender")
+	flag.BoolVar(&cfg.problemJSON, "problem-json", false, "Always write errors as RFC 7807 application/problem+json")
+	flag.DurationVar(&cfg.trash.retention, "trash-retention", 30*24*time.Hour, "How long deleted movies stay restorable (0 keeps them forever)")
+	flag.DurationVar(&cfg.trash.interval, "trash-purge-interval", time.Hour, "How often expired movies are purged from the trash")
 	flag.Func("cors-trusted-origins", "Trusted CORS origins (space separated)", func(s string) error {
 		cfg.cors.trustedOrigins = strings.Fields(s)
 		return nil
@@ -78,8 +115,15 @@ func main() {
 
 	l := jsonlog.New(os.Stdout, jsonlog.LevelInfo)
 	app := application{
-		logger: l,
-		config: cfg,
+		logger:      l,
+		config:      cfg,
+		instruments: newAppMetrics(),
+	}
+	if cfg.db.queryTimeout <= 0 {
+		app.logger.PrintFatal(errors.New("-db-query-timeout must be greater than zero"), nil)
+	}
+	if cfg.trash.interval <= 0 {
+		app.logger.PrintFatal(errors.New("-trash-purge-interval must be greater than zero"), nil)
 	}
 	mailer, err := mailer.New(cfg.smtp.host, cfg.smtp.port, cfg.smtp.username, cfg.smtp.password, cfg.smtp.sender)
 	if err != nil {
@@ -87,15 +131,83 @@ func main() {
 	}
 	app.mailer = mailer
 
-	models, err := data.NewModels()
-	if err != nil {
-		app.logger.PrintFatal(err, nil)
+	switch cfg.storage {
+	case "memory":
+		if args := flag.Args(); len(args) > 0 && args[0] == "migrate" {
+			app.logger.PrintFatal(errors.New("migrations need -storage=database"), nil)
+		}
+		app.models = data.NewMemoryModels()
+		app.logger.PrintInfo("using in-memory storage, nothing will be persisted", nil)
+	case "database", "postgres":
+		db, err := data.OpenDB(data.DBConfig{
+			Driver:       cfg.db.driver,
+			DSN:          cfg.db.dsn,
+			MaxOpenConns: cfg.db.maxOpenConns,
+			MaxIdleConns: cfg.db.maxIdleConns,
+			MaxIdleTime:  cfg.db.maxIdleTime,
+		})
+		if err != nil {
+			app.logger.PrintFatal(err, nil)
+		}
+		defer db.Close()
+		app.db = db
+		if cfg.db.driver == data.DriverSQLite {
+			app.models = data.NewSQLiteModels(db, cfg.db.queryTimeout)
+		} else {
+			app.models = data.NewModels(db, cfg.db.queryTimeout)
+		}
+		app.logger.PrintInfo("database connection estblished", map[string]string{
+			"driver": cfg.db.driver,
+		})
+
+		if args := flag.Args(); len(args) > 0 && args[0] == "migrate" {
+			err = app.runMigrate(db, args[1:])
+			if err != nil {
+				app.logger.PrintFatal(err, nil)
+			}
+			return
+		}
+		if cfg.db.migrate {
+			err = app.runMigrate(db, []string{"up"})
+			if err != nil {
+				app.logger.PrintFatal(err, nil)
+			}
+		}
+	default:
+		app.logger.PrintFatal(fmt.Errorf("unknown storage backend %q", cfg.storage), nil)
 	}
-	app.models = models
-	app.logger.PrintInfo("database connection estblished", nil)
 
 	err = app.serve()
 	if err != nil {
 		app.logger.PrintFatal(err, nil)
 	}
 }
+
+// envString returns the value of the named environment variable, or
+// defaultValue if it is unset or empty.
+func envString(key, defaultValue string) string {
+	if s := os.Getenv(key); s != "" {
+		return s
+	}
+	return defaultValue
+}
+
+// envInt returns the integer value of the named environment variable, or
+// defaultValue if it is unset or malformed.
+func envInt(key string, defaultValue int) int {
+	i, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return i
+}
+
+// envDuration returns the duration value of the named environment variable,
+// or defaultValue if it is unset or malformed.
+func envDuration(key string, defaultValue time.Duration) time.Duration {
+	d, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return d
+}
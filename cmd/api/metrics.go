@@ -0,0 +1,126 @@
+package main
+
+import (
+	"net/http"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/arian-nj/site/back/internal/metrics"
+)
+
+type appMetrics struct {
+	requests *metrics.CounterVec
+	duration *metrics.HistogramVec
+}
+
+func newAppMetrics() *appMetrics {
+	return &appMetrics{
+		requests: metrics.NewCounterVec("http_requests_total",
+			"Total HTTP requests processed.", "method", "route", "status"),
+		duration: metrics.NewHistogramVec("http_request_duration_seconds",
+			"HTTP request latency in seconds.", metrics.DefaultBuckets, "method", "route"),
+	}
+}
+
+// metricsResponseWriter records the status code and the number of body
+// bytes written by the handlers further down the chain.
+type metricsResponseWriter struct {
+	http.ResponseWriter
+	statusCode    int
+	bytes         int
+	headerWritten bool
+}
+
+func (mw *metricsResponseWriter) WriteHeader(statusCode int) {
+	if !mw.headerWritten {
+		mw.statusCode = statusCode
+		mw.headerWritten = true
+	}
+	mw.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (mw *metricsResponseWriter) Write(b []byte) (int, error) {
+	mw.headerWritten = true
+	n, err := mw.ResponseWriter.Write(b)
+	mw.bytes += n
+	return n, err
+}
+
+func (mw *metricsResponseWriter) Unwrap() http.ResponseWriter {
+	return mw.ResponseWriter
+}
+
+func (app *application) metrics(mux *http.ServeMux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		mw := &metricsResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+
+		next.ServeHTTP(mw, r)
+
+		method, route := methodLabel(r.Method), routePattern(mux, r)
+		app.instruments.requests.Inc(method, route, strconv.Itoa(mw.statusCode))
+		app.instruments.duration.Observe(time.Since(start).Seconds(), method, route)
+	})
+}
+
+// methodLabel() maps methods outside the standard set to "other", as
+// clients may send any token as the method.
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	}
+	return "other"
+}
+
+// routePattern() returns the path of the pattern the request was routed by,
+// such as /v1/movies/{id}, so ids in the path don't each become their own
+// series. The method is left off as it has a label of its own.
+func routePattern(mux *http.ServeMux, r *http.Request) string {
+	_, pattern := mux.Handler(r)
+	if pattern == "" {
+		return "unmatched"
+	}
+	if _, path, ok := strings.Cut(pattern, " "); ok {
+		return path
+	}
+	return pattern
+}
+
+func (app *application) metricsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	app.instruments.requests.WriteTo(w)
+	app.instruments.duration.WriteTo(w)
+
+	metrics.WriteGauge(w, "app_background_tasks", "Background goroutines currently running.",
+		float64(app.backgroundTasks.Load()))
+	metrics.WriteGauge(w, "go_goroutines", "Number of goroutines that currently exist.",
+		float64(runtime.NumGoroutine()))
+
+	if app.db == nil {
+		return
+	}
+	stats := app.db.Stats()
+	metrics.WriteGauge(w, "db_max_open_connections", "Maximum number of open connections to the database.",
+		float64(stats.MaxOpenConnections))
+	metrics.WriteGauge(w, "db_open_connections", "The number of established connections both in use and idle.",
+		float64(stats.OpenConnections))
+	metrics.WriteGauge(w, "db_in_use_connections", "The number of connections currently in use.",
+		float64(stats.InUse))
+	metrics.WriteGauge(w, "db_idle_connections", "The number of idle connections.",
+		float64(stats.Idle))
+	metrics.WriteCounter(w, "db_wait_count_total", "The total number of connections waited for.",
+		float64(stats.WaitCount))
+	metrics.WriteCounter(w, "db_wait_duration_seconds_total", "The total time blocked waiting for a new connection.",
+		stats.WaitDuration.Seconds())
+	metrics.WriteCounter(w, "db_max_idle_closed_total", "The total number of connections closed due to SetMaxIdleConns.",
+		float64(stats.MaxIdleClosed))
+	metrics.WriteCounter(w, "db_max_idle_time_closed_total", "The total number of connections closed due to SetConnMaxIdleTime.",
+		float64(stats.MaxIdleTimeClosed))
+	metrics.WriteCounter(w, "db_max_lifetime_closed_total", "The total number of connections closed due to SetConnMaxLifetime.",
+		float64(stats.MaxLifetimeClosed))
+}
@@ -1,11 +1,13 @@
 package main
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"net"
 	"net/http"
-	"strings"
+	"strconv"
 	"sync"
 	"time"
 
@@ -14,6 +16,79 @@ import (
 	"golang.org/x/time/rate"
 )
 
+// requestIDHeader carries the ID that ties a request to its log lines.
+const requestIDHeader = "X-Request-ID"
+
+// requestID() takes the request ID sent by the client, or assigns a new one,
+// and returns it in the response headers.
+func (app *application) requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(requestIDHeader, id)
+		next.ServeHTTP(w, app.contextSetRequestID(r, id))
+	})
+}
+
+// validRequestID() reports whether a client-supplied ID is safe to echo back
+// and log: 1 to 128 ASCII letters, digits, '-', '_', '.' or ':'.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(b)
+}
+
+// accessLogEntry collects what the access log needs from further down the
+// middleware chain; contextSetUser() fills in the user.
+type accessLogEntry struct {
+	user *data.User
+}
+
+// logRequests() writes one access log line for every request once it has
+// been served.
+func (app *application) logRequests(mux *http.ServeMux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		mw := &metricsResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		entry := &accessLogEntry{}
+
+		next.ServeHTTP(mw, app.contextSetAccessLogEntry(r, entry))
+
+		properties := map[string]string{
+			"request_id": app.contextGetRequestID(r),
+			"method":     r.Method,
+			"route":      routePattern(mux, r),
+			"status":     strconv.Itoa(mw.statusCode),
+			"bytes":      strconv.Itoa(mw.bytes),
+			"duration":   time.Since(start).String(),
+			"remote_ip":  clientIP(r),
+		}
+		if entry.user != nil && !entry.user.IsAnonymous() {
+			properties["user_id"] = strconv.FormatInt(entry.user.ID, 10)
+		}
+		app.logger.PrintInfo("request completed", properties)
+	})
+}
+
 func (app *application) recoverPanic(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
@@ -65,7 +140,7 @@ func (app *application) rateLimit(next http.Handler) http.Handler {
 			cl.lastSeen = time.Now()
 			if !cl.limiter.Allow() {
 				mu.Unlock()
-				app.toManyRequestsResponse(w)
+				app.toManyRequestsResponse(w, r)
 				return
 			}
 			mu.Unlock()
@@ -85,20 +160,19 @@ func (app *application) authentication(next http.Handler) http.Handler {
 			return
 		}
 
-		headerParts := strings.Split(authorizationHeader, " ")
-		if len(headerParts) != 2 || headerParts[0] != "Bearer" {
+		token, ok := bearerToken(r)
+		if !ok {
 			app.invalidAuthenticationTokenResponse(w, r)
 			return
 		}
 
-		token := headerParts[1]
 		v := validator.New()
 		if data.ValidateTokenPlainText(v, token); !v.Valid() {
 			app.invalidAuthenticationTokenResponse(w, r)
 
 			return
 		}
-		user, err := app.models.Users.GetForToken(data.ScopeAuthentication, token)
+		user, err := app.models.Users.GetForToken(r.Context(), data.ScopeAuthentication, token)
 		if err != nil {
 			if errors.Is(err, data.ErrRecordNotFound) {
 				app.invalidAuthenticationTokenResponse(w, r)
@@ -139,6 +213,24 @@ func (app *application) requireActivatedUser(next http.HandlerFunc) http.Handler
 	return app.requireAuthenticatedUser(fn)
 }
 
+func (app *application) requirePermission(code string, next http.HandlerFunc) http.HandlerFunc {
+
+	fn := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := app.contextGetUser(r)
+		permissions, err := app.models.Permissions.GetAllForUser(r.Context(), user.ID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		if !permissions.Include(code) {
+			app.notPermittedResponse(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+	return app.requireActivatedUser(fn)
+}
+
 func (app *application) enableCORS(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Add("Vary", "Origin")
@@ -149,9 +241,10 @@ func (app *application) enableCORS(next http.Handler) http.Handler {
 			for _, o := range app.config.cors.trustedOrigins {
 				if origin == o {
 					w.Header().Set("Access-Control-Allow-Origin", origin)
+					w.Header().Set("Access-Control-Expose-Headers", "ETag, X-Request-ID")
 					if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
 						w.Header().Set("Access-Control-Allow-Methods", "OPTIONS, PUT, PATCH, DELETE")
-						w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+						w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, If-Match, If-None-Match, X-Request-ID")
 						w.WriteHeader(http.StatusOK)
 						return
 					}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/arian-nj/site/back/internal/data"
+	"github.com/arian-nj/site/back/internal/migrate"
+	"github.com/arian-nj/site/back/migrations"
+)
+
+// runMigrate() handles the `migrate up|down|status|goto N` subcommand.
+func (app *application) runMigrate(db *sql.DB, args []string) error {
+	fsys, err := migrations.ForDriver(app.config.db.driver)
+	if err != nil {
+		return err
+	}
+	m, err := migrate.New(db, fsys)
+	if err != nil {
+		return err
+	}
+	if app.config.db.driver == data.DriverSQLite {
+		m.Dialect = migrate.SQLite
+	}
+	if len(args) == 0 {
+		return errors.New("usage: migrate up|down|status|goto N")
+	}
+
+	switch args[0] {
+	case "up":
+		err = m.Up()
+	case "down":
+		err = m.Down()
+	case "goto":
+		if len(args) != 2 {
+			return errors.New("usage: migrate goto N")
+		}
+		version, perr := strconv.ParseInt(args[1], 10, 64)
+		if perr != nil {
+			return fmt.Errorf("invalid migration version %q", args[1])
+		}
+		err = m.Goto(version)
+	case "status":
+		statuses, serr := m.Status()
+		if serr != nil {
+			return serr
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range statuses {
+			appliedAt := "pending"
+			if s.Applied {
+				appliedAt = s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(tw, "%d\t%s\t%s\n", s.Version, s.Name, appliedAt)
+		}
+		return tw.Flush()
+	default:
+		return fmt.Errorf("unknown migrate command %q", args[0])
+	}
+	if err != nil {
+		return err
+	}
+	app.logger.PrintInfo("database migrations complete", map[string]string{
+		"command": args[0],
+	})
+	return nil
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/arian-nj/site/back/internal/data"
+	"github.com/arian-nj/site/back/internal/validator"
+)
+
+func (app *application) createPersonHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name      string `json:"name"`
+		BirthYear *int32 `json:"birth_year"`
+		Bio       string `json:"bio"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	person := &data.Person{
+		Name:      input.Name,
+		BirthYear: input.BirthYear,
+		Bio:       input.Bio,
+	}
+	v := validator.New()
+	if data.ValidatePerson(v, person); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.People.Insert(r.Context(), person)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = writeJSON(w, http.StatusCreated, envelope{"person": person})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) getPersonHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := readParamId(r)
+	if err != nil {
+		app.CustomErrResponse(w, r, http.StatusNotFound, err)
+		return
+	}
+
+	person, err := app.models.People.Get(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			app.notFoundResponse(w, r)
+		} else {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = writeJSON(w, http.StatusOK, envelope{"person": person})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updatePersonHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := readParamId(r)
+	if err != nil {
+		app.CustomErrResponse(w, r, http.StatusNotFound, err)
+		return
+	}
+
+	person, err := app.models.People.Get(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			app.notFoundResponse(w, r)
+		} else {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		Name      *string `json:"name"`
+		BirthYear *int32  `json:"birth_year"`
+		Bio       *string `json:"bio"`
+	}
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Name != nil {
+		person.Name = *input.Name
+	}
+	if input.BirthYear != nil {
+		person.BirthYear = input.BirthYear
+	}
+	if input.Bio != nil {
+		person.Bio = *input.Bio
+	}
+
+	v := validator.New()
+	if data.ValidatePerson(v, person); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.People.Update(r.Context(), person)
+	if err != nil {
+		if errors.Is(err, data.ErrEditConflict) {
+			app.editConflictResponse(w, r)
+		} else {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = writeJSON(w, http.StatusOK, envelope{"person": person})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deletePersonHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := readParamId(r)
+	if err != nil {
+		app.CustomErrResponse(w, r, http.StatusNotFound, err)
+		return
+	}
+
+	err = app.models.People.Delete(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			app.notFoundResponse(w, r)
+		} else {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = writeJSON(w, http.StatusOK, envelope{"message": "person successfully deleted"})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listPeopleHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name string
+		data.Filters
+	}
+	v := validator.New()
+	qs := r.URL.Query()
+
+	input.Name = app.readString(qs, "name", "")
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", "id")
+	input.Filters.SortSafelist = []string{"id", "name", "birth_year", "-id", "-name", "-birth_year"}
+
+	data.ValidateFilter(v, input.Filters)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	people, metadata, err := app.models.People.GetAll(r.Context(), input.Name, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = writeJSON(w, http.StatusOK, envelope{"metadata": metadata, "people": people})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) filmographyHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := readParamId(r)
+	if err != nil {
+		app.CustomErrResponse(w, r, http.StatusNotFound, err)
+		return
+	}
+
+	person, err := app.models.People.Get(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			app.notFoundResponse(w, r)
+		} else {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	credits, err := app.models.Credits.GetAllForPerson(r.Context(), person.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = writeJSON(w, http.StatusOK, envelope{"person": person, "filmography": credits})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
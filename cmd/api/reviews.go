@@ -0,0 +1,183 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/arian-nj/site/back/internal/data"
+	"github.com/arian-nj/site/back/internal/validator"
+)
+
+func (app *application) createReviewHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := readParamId(r)
+	if err != nil {
+		app.CustomErrResponse(w, r, http.StatusNotFound, err)
+		return
+	}
+
+	var input struct {
+		Score int32  `json:"score"`
+		Body  string `json:"body"`
+	}
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	review := &data.Review{
+		UserID:   app.contextGetUser(r).ID,
+		UserName: app.contextGetUser(r).Name,
+		MovieID:  id,
+		Score:    input.Score,
+		Body:     input.Body,
+	}
+	v := validator.New()
+	if data.ValidateReview(v, review); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Reviews.Insert(r.Context(), review)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, data.ErrDuplicateReview):
+			v.AddError("movie", "you have already reviewed this movie")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = writeJSON(w, http.StatusCreated, envelope{"review": review})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateReviewHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := readParamId(r)
+	if err != nil {
+		app.CustomErrResponse(w, r, http.StatusNotFound, err)
+		return
+	}
+
+	review, err := app.models.Reviews.Get(r.Context(), app.contextGetUser(r).ID, id)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			app.notFoundResponse(w, r)
+		} else {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		Score *int32  `json:"score"`
+		Body  *string `json:"body"`
+	}
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if input.Score != nil {
+		review.Score = *input.Score
+	}
+	if input.Body != nil {
+		review.Body = *input.Body
+	}
+
+	v := validator.New()
+	if data.ValidateReview(v, review); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Reviews.Update(r.Context(), review)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = writeJSON(w, http.StatusOK, envelope{"review": review})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteReviewHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := readParamId(r)
+	if err != nil {
+		app.CustomErrResponse(w, r, http.StatusNotFound, err)
+		return
+	}
+
+	err = app.models.Reviews.Delete(r.Context(), app.contextGetUser(r).ID, id)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			app.notFoundResponse(w, r)
+		} else {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = writeJSON(w, http.StatusOK, envelope{"message": "review successfully deleted"})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listReviewsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := readParamId(r)
+	if err != nil {
+		app.CustomErrResponse(w, r, http.StatusNotFound, err)
+		return
+	}
+
+	_, err = app.models.Movie.Get(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			app.notFoundResponse(w, r)
+		} else {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var filters data.Filters
+	v := validator.New()
+	qs := r.URL.Query()
+	filters.Page = app.readInt(qs, "page", 1, v)
+	filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	filters.Sort = app.readString(qs, "sort", "-created_at")
+	filters.SortSafelist = []string{"created_at", "score", "-created_at", "-score"}
+
+	data.ValidateFilter(v, filters)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	reviews, metadata, err := app.models.Reviews.GetAllForMovie(r.Context(), id, filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = writeJSON(w, http.StatusOK, envelope{"metadata": metadata, "reviews": reviews})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/arian-nj/site/back/internal/data"
+)
+
+func (app *application) listMovieRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := readParamId(r)
+	if err != nil {
+		app.CustomErrResponse(w, r, http.StatusNotFound, err)
+		return
+	}
+
+	_, err = app.models.Movie.Get(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			app.notFoundResponse(w, r)
+		} else {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	revisions, err := app.models.Revisions.GetAllForMovie(r.Context(), id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = writeJSON(w, http.StatusOK, envelope{"revisions": revisions})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) getMovieRevisionHandler(w http.ResponseWriter, r *http.Request) {
+	_, revision, ok := app.readRevision(w, r)
+	if !ok {
+		return
+	}
+
+	err := writeJSON(w, http.StatusOK, envelope{"revision": revision})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// restoreMovieRevisionHandler() copies an old revision over the current
+// movie. It is saved through Movie.Update() like any other edit, so the
+// replaced version becomes a revision in turn.
+func (app *application) restoreMovieRevisionHandler(w http.ResponseWriter, r *http.Request) {
+	movie, revision, ok := app.readRevision(w, r)
+	if !ok {
+		return
+	}
+
+	movie.Title = revision.Title
+	movie.Year = revision.Year
+	movie.Runtime = revision.Runtime
+	movie.Genres = revision.Genres
+
+	err := app.models.Movie.Update(r.Context(), movie, app.contextGetUser(r).ID)
+	if err != nil {
+		if errors.Is(err, data.ErrEditConflict) {
+			app.editConflictResponse(w, r)
+		} else {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = writeJSON(w, http.StatusOK, envelope{"movie": movie})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readRevision() loads the live movie and its revision named by the id and
+// version path parameters, writing an error response and returning false if
+// it can't.
+func (app *application) readRevision(w http.ResponseWriter, r *http.Request) (*data.Movie, *data.Revision, bool) {
+	id, err := readParamId(r)
+	if err != nil {
+		app.CustomErrResponse(w, r, http.StatusNotFound, err)
+		return nil, nil, false
+	}
+	version, err := strconv.ParseInt(r.PathValue("version"), 10, 32)
+	if err != nil {
+		app.CustomErrResponse(w, r, http.StatusNotFound, errors.New("invalid version parameter"))
+		return nil, nil, false
+	}
+
+	movie, err := app.models.Movie.Get(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			app.notFoundResponse(w, r)
+		} else {
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, nil, false
+	}
+
+	revision, err := app.models.Revisions.Get(r.Context(), id, int32(version))
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			app.notFoundResponse(w, r)
+		} else {
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, nil, false
+	}
+	return movie, revision, true
+}
@@ -2,32 +2,139 @@ package main
 
 import (
 	"net/http"
+	"strings"
 
-	"github.com/julienschmidt/httprouter"
+	"github.com/arian-nj/site/back/internal/data"
 )
 
 func (app *application) makeRouter() http.Handler {
-	router := httprouter.New()
-	router.NotFound = http.HandlerFunc(app.notFoundResponse)
-	router.MethodNotAllowed = http.HandlerFunc(app.methodNotAllowed)
+	mux := http.NewServeMux()
 
-	router.HandlerFunc(http.MethodGet, "/v1/healthcheck", app.healthcheckHandler)
+	mux.HandleFunc("GET /v1/healthcheck", app.healthcheckHandler)
+	mux.HandleFunc("GET /debug/metrics", app.metricsHandler)
 	// movies
-	router.HandlerFunc(http.MethodPost, "/v1/movies",
-		app.requireActivatedUser(app.createMovieHandler))
-	router.HandlerFunc(http.MethodGet, "/v1/movies/{id}",
-		app.requireActivatedUser(app.getMovieHandler))
-	router.HandlerFunc(http.MethodPatch, "/v1/movies/{id}",
-		app.requireActivatedUser(app.updateMovieHandler))
-	router.HandlerFunc(http.MethodDelete, "/v1/movies/{id}",
-		app.requireActivatedUser(app.deleteMovieHandler))
-	router.HandlerFunc(http.MethodGet, "/v1/movies",
-		app.requireActivatedUser(app.listMovieHandler))
+	mux.HandleFunc("POST /v1/movies",
+		app.requirePermission(data.PermissionMoviesWrite, app.createMovieHandler))
+	mux.HandleFunc("POST /v1/movies/import",
+		app.requirePermission(data.PermissionMoviesWrite, app.importMovieHandler))
+	mux.HandleFunc("GET /v1/movies/trash",
+		app.requirePermission(data.PermissionMoviesWrite, app.listTrashHandler))
+	mux.HandleFunc("POST /v1/movies/{id}/restore",
+		app.requirePermission(data.PermissionMoviesWrite, app.restoreMovieHandler))
+	mux.HandleFunc("GET /v1/movies/suggest",
+		app.requirePermission(data.PermissionMoviesRead, app.suggestMovieHandler))
+	mux.HandleFunc("GET /v1/movies/export",
+		app.requirePermission(data.PermissionMoviesRead, app.exportMovieHandler))
+	mux.HandleFunc("GET /v1/movies/{id}",
+		app.requirePermission(data.PermissionMoviesRead, app.getMovieHandler))
+	mux.HandleFunc("PATCH /v1/movies/{id}",
+		app.requirePermission(data.PermissionMoviesWrite, app.updateMovieHandler))
+	mux.HandleFunc("DELETE /v1/movies/{id}",
+		app.requirePermission(data.PermissionMoviesWrite, app.deleteMovieHandler))
+	mux.HandleFunc("GET /v1/movies",
+		app.requirePermission(data.PermissionMoviesRead, app.listMovieHandler))
+	mux.HandleFunc("GET /v1/movies/{id}/revisions",
+		app.requirePermission(data.PermissionMoviesRead, app.listMovieRevisionsHandler))
+	mux.HandleFunc("GET /v1/movies/{id}/revisions/{version}",
+		app.requirePermission(data.PermissionMoviesRead, app.getMovieRevisionHandler))
+	mux.HandleFunc("POST /v1/movies/{id}/revisions/{version}/restore",
+		app.requirePermission(data.PermissionMoviesWrite, app.restoreMovieRevisionHandler))
+	mux.HandleFunc("GET /v1/movies/{id}/reviews",
+		app.requirePermission(data.PermissionMoviesRead, app.listReviewsHandler))
+	mux.HandleFunc("POST /v1/movies/{id}/reviews",
+		app.requirePermission(data.PermissionMoviesRead, app.createReviewHandler))
+	mux.HandleFunc("PATCH /v1/movies/{id}/reviews",
+		app.requirePermission(data.PermissionMoviesRead, app.updateReviewHandler))
+	mux.HandleFunc("DELETE /v1/movies/{id}/reviews",
+		app.requirePermission(data.PermissionMoviesRead, app.deleteReviewHandler))
+	mux.HandleFunc("GET /v1/movies/{id}/credits",
+		app.requirePermission(data.PermissionMoviesRead, app.listMovieCreditsHandler))
+	mux.HandleFunc("POST /v1/movies/{id}/credits",
+		app.requirePermission(data.PermissionMoviesWrite, app.createMovieCreditHandler))
+	mux.HandleFunc("DELETE /v1/movies/{id}/credits/{credit_id}",
+		app.requirePermission(data.PermissionMoviesWrite, app.deleteMovieCreditHandler))
+	// people
+	mux.HandleFunc("POST /v1/people",
+		app.requirePermission(data.PermissionMoviesWrite, app.createPersonHandler))
+	mux.HandleFunc("GET /v1/people",
+		app.requirePermission(data.PermissionMoviesRead, app.listPeopleHandler))
+	mux.HandleFunc("GET /v1/people/{id}",
+		app.requirePermission(data.PermissionMoviesRead, app.getPersonHandler))
+	mux.HandleFunc("PATCH /v1/people/{id}",
+		app.requirePermission(data.PermissionMoviesWrite, app.updatePersonHandler))
+	mux.HandleFunc("DELETE /v1/people/{id}",
+		app.requirePermission(data.PermissionMoviesWrite, app.deletePersonHandler))
+	mux.HandleFunc("GET /v1/people/{id}/filmography",
+		app.requirePermission(data.PermissionMoviesRead, app.filmographyHandler))
 	// user
-	router.HandlerFunc(http.MethodPost, "/v1/users", app.registerUserHandler)
-	router.HandlerFunc(http.MethodPut, "/v1/users/activated", app.activateUserHandler)
+	mux.HandleFunc("POST /v1/users", app.registerUserHandler)
+	mux.HandleFunc("PUT /v1/users/activated", app.activateUserHandler)
+	mux.HandleFunc("PUT /v1/users/password", app.updateUserPasswordHandler)
+	mux.HandleFunc("GET /v1/users/me/sessions",
+		app.requireAuthenticatedUser(app.listSessionsHandler))
+	// lists
+	mux.HandleFunc("GET /v1/users/me/lists",
+		app.requirePermission(data.PermissionMoviesRead, app.listListsHandler))
+	mux.HandleFunc("POST /v1/users/me/lists",
+		app.requirePermission(data.PermissionMoviesRead, app.createListHandler))
+	mux.HandleFunc("GET /v1/users/me/lists/{id}",
+		app.requirePermission(data.PermissionMoviesRead, app.getListHandler))
+	mux.HandleFunc("PATCH /v1/users/me/lists/{id}",
+		app.requirePermission(data.PermissionMoviesRead, app.updateListHandler))
+	mux.HandleFunc("DELETE /v1/users/me/lists/{id}",
+		app.requirePermission(data.PermissionMoviesRead, app.deleteListHandler))
+	mux.HandleFunc("GET /v1/users/me/lists/{id}/movies",
+		app.requirePermission(data.PermissionMoviesRead, app.listListMoviesHandler))
+	mux.HandleFunc("POST /v1/users/me/lists/{id}/movies",
+		app.requirePermission(data.PermissionMoviesRead, app.addListMovieHandler))
+	mux.HandleFunc("PATCH /v1/users/me/lists/{id}/movies/{movie_id}",
+		app.requirePermission(data.PermissionMoviesRead, app.moveListMovieHandler))
+	mux.HandleFunc("DELETE /v1/users/me/lists/{id}/movies/{movie_id}",
+		app.requirePermission(data.PermissionMoviesRead, app.removeListMovieHandler))
+	mux.HandleFunc("GET /v1/lists/{id}",
+		app.requirePermission(data.PermissionMoviesRead, app.getPublicListHandler))
+	mux.HandleFunc("GET /v1/lists/{id}/movies",
+		app.requirePermission(data.PermissionMoviesRead, app.listPublicListMoviesHandler))
 
-	router.HandlerFunc(http.MethodPost, "/v1/tokens/authentication", app.createAuthenticationTokenHandler)
+	mux.HandleFunc("POST /v1/tokens/authentication", app.createAuthenticationTokenHandler)
+	mux.HandleFunc("DELETE /v1/tokens/authentication",
+		app.requireAuthenticatedUser(app.deleteAuthenticationTokenHandler))
+	mux.HandleFunc("DELETE /v1/tokens/authentication/all",
+		app.requireAuthenticatedUser(app.deleteAllAuthenticationTokensHandler))
+	mux.HandleFunc("POST /v1/tokens/password-reset", app.createPasswordResetTokenHandler)
 
-	return app.recoverPanic(app.enableCORS(app.rateLimit(app.authentication(router))))
+	return app.requestID(app.logRequests(mux,
+		app.metrics(mux, app.recoverPanic(app.enableCORS(app.rateLimit(app.authentication(app.serveMux(mux))))))))
+}
+
+// serveMux() hands requests that match a route to mux and answers the rest
+// with the API's own not found and method not allowed responses in place of
+// the plain text ones http.ServeMux writes.
+func (app *application) serveMux(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, pattern := mux.Handler(r); pattern != "" {
+			mux.ServeHTTP(w, r)
+			return
+		}
+		if allowed := allowedMethods(mux, r); len(allowed) > 0 {
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			app.methodNotAllowed(w, r)
+			return
+		}
+		app.notFoundResponse(w, r)
+	})
+}
+
+// allowedMethods() lists the methods mux has a route for at the path of r.
+func allowedMethods(mux *http.ServeMux, r *http.Request) []string {
+	var allowed []string
+	for _, method := range []string{http.MethodGet, http.MethodHead, http.MethodPost,
+		http.MethodPut, http.MethodPatch, http.MethodDelete} {
+		probe := r.Clone(r.Context())
+		probe.Method = method
+		if _, pattern := mux.Handler(probe); pattern != "" {
+			allowed = append(allowed, method)
+		}
+	}
+	return allowed
 }
@@ -24,6 +24,12 @@ func (app *application) serve() error {
 		WriteTimeout: 30 * time.Second,
 	}
 
+	// stopBackground tells long-running background tasks to finish before
+	// app.wg.Wait() is called on shutdown.
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+	app.purgeTrash(backgroundCtx)
+
 	shutDownErr := make(chan error)
 	go func() {
 		quit := make(chan os.Signal, 1)
@@ -38,6 +44,7 @@ func (app *application) serve() error {
 		if err != nil {
 			shutDownErr <- err
 		}
+		stopBackground()
 		app.logger.PrintInfo("completing background tasks",
 			map[string]string{
 				"addr": srv.Addr,
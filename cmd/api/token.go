@@ -17,7 +17,7 @@ func (app *application) createAuthenticationTokenHandler(w http.ResponseWriter,
 
 	err := app.readJSON(w, r, &input)
 	if err != nil {
-		app.badRequestResponse(w, err)
+		app.badRequestResponse(w, r, err)
 		return
 	}
 
@@ -25,11 +25,11 @@ func (app *application) createAuthenticationTokenHandler(w http.ResponseWriter,
 	data.ValidateEmail(v, input.Email)
 	data.ValidatePasswordPlainText(v, input.Password)
 	if !v.Valid() {
-		app.failedValidationResponse(w, v.Errors)
+		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	user, err := app.models.Users.GetByEmail(input.Email)
+	user, err := app.models.Users.GetByEmail(r.Context(), input.Email)
 	if err != nil {
 		if errors.Is(err, data.ErrRecordNotFound) {
 			app.invalidcredentialsResponse(w, r)
@@ -48,7 +48,7 @@ func (app *application) createAuthenticationTokenHandler(w http.ResponseWriter,
 		app.invalidcredentialsResponse(w, r)
 		return
 	}
-	token, err := app.models.Token.New(user.ID, 1*time.Hour, data.ScopeAuthentication)
+	token, err := app.models.Token.NewSession(r.Context(), user.ID, 1*time.Hour, r.UserAgent(), clientIP(r))
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -58,3 +58,99 @@ func (app *application) createAuthenticationTokenHandler(w http.ResponseWriter,
 		app.serverErrorResponse(w, r, err)
 	}
 }
+
+func (app *application) createPasswordResetTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Email string `json:"email"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if data.ValidateEmail(v, input.Email); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// Every well-formed address gets the same answer, so the endpoint can't
+	// be used to find out which addresses have an activated account.
+	user, err := app.models.Users.GetByEmail(r.Context(), input.Email)
+	switch {
+	case errors.Is(err, data.ErrRecordNotFound):
+	case err != nil:
+		app.serverErrorResponse(w, r, err)
+		return
+	case user.Activated:
+		token, err := app.models.Token.New(r.Context(), user.ID, 45*time.Minute, data.ScopePasswordReset)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		app.background(func() {
+			data := map[string]interface{}{
+				"passwordResetToken": token.Plaintext,
+			}
+			err := app.mailer.Send(user.Email, "token_password_reset.tmpl", data)
+			if err != nil {
+				app.logger.PrintError(err, nil)
+			}
+		})
+	}
+
+	err = writeJSON(w, http.StatusAccepted, envelope{"message": "if an activated account uses this email address, you will receive password reset instructions"})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteAuthenticationTokenHandler(w http.ResponseWriter, r *http.Request) {
+	token, _ := bearerToken(r)
+	err := app.models.Token.DeleteByPlaintext(r.Context(), data.ScopeAuthentication, token)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			app.invalidAuthenticationTokenResponse(w, r)
+		} else {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = writeJSON(w, http.StatusOK, envelope{"message": "authentication token revoked"})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteAllAuthenticationTokensHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	err := app.models.Token.DeleteAllForUser(r.Context(), data.ScopeAuthentication, user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = writeJSON(w, http.StatusOK, envelope{"message": "all authentication tokens revoked"})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	token, _ := bearerToken(r)
+	sessions, err := app.models.Token.GetSessionsForUser(r.Context(), user.ID, token)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = writeJSON(w, http.StatusOK, envelope{"sessions": sessions})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/arian-nj/site/back/internal/data"
+	"github.com/arian-nj/site/back/internal/validator"
+)
+
+func (app *application) listTrashHandler(w http.ResponseWriter, r *http.Request) {
+	var filters data.Filters
+	v := validator.New()
+	qs := r.URL.Query()
+	filters.Page = app.readInt(qs, "page", 1, v)
+	filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	filters.Sort = "-deleted_at"
+	filters.SortSafelist = []string{"-deleted_at"}
+
+	data.ValidateFilter(v, filters)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	movies, metadata, err := app.models.Movie.GetDeleted(r.Context(), filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = writeJSON(w, http.StatusOK, envelope{"metadata": metadata, "movies": movies})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) restoreMovieHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := readParamId(r)
+	if err != nil {
+		app.CustomErrResponse(w, r, http.StatusNotFound, err)
+		return
+	}
+
+	movie, err := app.models.Movie.Restore(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			app.notFoundResponse(w, r)
+		} else {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = writeJSON(w, http.StatusOK, envelope{"movie": movie})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// purgeTrash() permanently removes movies that have been in the trash for
+// longer than the configured retention, checking every interval until ctx
+// is cancelled.
+func (app *application) purgeTrash(ctx context.Context) {
+	if app.config.trash.retention <= 0 {
+		return
+	}
+	app.background(func() {
+		ticker := time.NewTicker(app.config.trash.interval)
+		defer ticker.Stop()
+		for {
+			purged, err := app.models.Movie.Purge(ctx, app.config.trash.retention)
+			if err != nil {
+				app.logger.PrintError(err, nil)
+			} else if purged > 0 {
+				app.logger.PrintInfo("purged movies from trash", map[string]string{
+					"count": strconv.FormatInt(purged, 10),
+				})
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	})
+}
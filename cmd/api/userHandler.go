@@ -17,7 +17,7 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 	}
 	err := app.readJSON(w, r, &input)
 	if err != nil {
-		app.badRequestResponse(w, err)
+		app.badRequestResponse(w, r, err)
 		return
 	}
 	user := data.User{
@@ -34,27 +34,36 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 	v := validator.New()
 	data.ValidateUser(v, &user)
 	if !v.Valid() {
-		app.failedValidationResponse(w, v.Errors)
+		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	err = app.models.Users.Insert(&user)
+	// The user, their default permission and the activation token are
+	// created together, so a failure never leaves an account that can't be
+	// activated.
+	var token *data.Token
+	err = app.models.WithTx(r.Context(), func(tx *data.Models) error {
+		err := tx.Users.Insert(r.Context(), &user)
+		if err != nil {
+			return err
+		}
+		err = tx.Permissions.AddForUser(r.Context(), user.ID, data.PermissionMoviesRead)
+		if err != nil {
+			return err
+		}
+		token, err = tx.Token.New(r.Context(), user.ID, 3*24*time.Hour, data.ScopeActivation)
+		return err
+	})
 	if err != nil {
 		if errors.Is(err, data.ErrDuplicateEmail) {
 			v.AddError("email", "a user with this email already exist")
-			app.failedValidationResponse(w, v.Errors)
+			app.failedValidationResponse(w, r, v.Errors)
 		} else {
 			app.serverErrorResponse(w, r, err)
 		}
 		return
 	}
 
-	token, err := app.models.Token.New(user.ID, 3*24*time.Hour, data.ScopeActivation)
-	if err != nil {
-		app.serverErrorResponse(w, r, err)
-		return
-	}
-
 	app.background(func() {
 		data := map[string]interface{}{
 			"userId":          user.ID,
@@ -80,21 +89,21 @@ func (app *application) activateUserHandler(w http.ResponseWriter, r *http.Reque
 	}
 	err := app.readJSON(w, r, &input)
 	if err != nil {
-		app.badRequestResponse(w, err)
+		app.badRequestResponse(w, r, err)
 		return
 	}
 
 	v := validator.New()
 	data.ValidateTokenPlainText(v, input.TokenPlaintext)
 	if !v.Valid() {
-		app.failedValidationResponse(w, v.Errors)
+		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
-	user, err := app.models.Users.GetForToken(data.ScopeActivation, input.TokenPlaintext)
+	user, err := app.models.Users.GetForToken(r.Context(), data.ScopeActivation, input.TokenPlaintext)
 	if err != nil {
 		if errors.Is(err, data.ErrRecordNotFound) {
 			v.AddError("token", "invalid or expierd activation token")
-			app.failedValidationResponse(w, v.Errors)
+			app.failedValidationResponse(w, r, v.Errors)
 		} else {
 			app.serverErrorResponse(w, r, err)
 		}
@@ -102,25 +111,89 @@ func (app *application) activateUserHandler(w http.ResponseWriter, r *http.Reque
 	}
 	user.Activated = true
 
-	err = app.models.Users.Update(user)
+	err = app.models.WithTx(r.Context(), func(tx *data.Models) error {
+		err := tx.Users.Update(r.Context(), user)
+		if err != nil {
+			return err
+		}
+		return tx.Token.DeleteAllForUser(r.Context(), data.ScopeActivation, user.ID)
+	})
 	if err != nil {
 		if errors.Is(err, data.ErrEditConflict) {
-			app.editConflictResponse(w)
+			app.editConflictResponse(w, r)
 		} else {
 			app.serverErrorResponse(w, r, err)
 		}
-
 		return
 	}
-	err = app.models.Token.DeleteAllForUser(data.ScopeActivation, user.ID)
+	// Send the updated user details to the client in a JSON response.
+	err = writeJSON(w, http.StatusOK, envelope{"user": user})
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
+	}
+
+}
+
+func (app *application) updateUserPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Password       string `json:"password"`
+		TokenPlaintext string `json:"token"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
 		return
 	}
-	// Send the updated user details to the client in a JSON response.
-	err = writeJSON(w, http.StatusOK, envelope{"user": user})
+
+	v := validator.New()
+	data.ValidatePasswordPlainText(v, input.Password)
+	data.ValidateTokenPlainText(v, input.TokenPlaintext)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user, err := app.models.Users.GetForToken(r.Context(), data.ScopePasswordReset, input.TokenPlaintext)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			v.AddError("token", "invalid or expired password reset token")
+			app.failedValidationResponse(w, r, v.Errors)
+		} else {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = user.Password.Set(input.Password)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// The reset token is single use, and any session opened with the old
+	// password must not survive the change.
+	err = app.models.WithTx(r.Context(), func(tx *data.Models) error {
+		err := tx.Users.Update(r.Context(), user)
+		if err != nil {
+			return err
+		}
+		err = tx.Token.DeleteAllForUser(r.Context(), data.ScopePasswordReset, user.ID)
+		if err != nil {
+			return err
+		}
+		return tx.Token.DeleteAllForUser(r.Context(), data.ScopeAuthentication, user.ID)
+	})
+	if err != nil {
+		if errors.Is(err, data.ErrEditConflict) {
+			app.editConflictResponse(w, r)
+		} else {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
 	}
 
+	err = writeJSON(w, http.StatusOK, envelope{"message": "your password was successfully reset"})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
 }
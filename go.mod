@@ -3,15 +3,25 @@ module github.com/arian-nj/site/back
 go 1.22.0
 
 require (
-	github.com/julienschmidt/httprouter v1.3.0
+	github.com/joho/godotenv v1.5.1
 	github.com/lib/pq v1.10.9
+	github.com/wneessen/go-mail v0.4.2
 	golang.org/x/crypto v0.25.0
 	golang.org/x/time v0.5.0
+	modernc.org/sqlite v1.36.0
 )
 
 require (
+	github.com/dustin/go-humanize v1.0.1 // indirect
 	github.com/go-mail/mail/v2 v2.3.0 // indirect
-	github.com/joho/godotenv v1.5.1 // indirect
-	github.com/wneessen/go-mail v0.4.2 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/mattn/go-isatty v0.0.20 // indirect
+	github.com/ncruces/go-strftime v0.1.9 // indirect
+	github.com/remyoudompheng/bigfft v0.0.0-20230129092748-24d4a6f8daec // indirect
+	golang.org/x/exp v0.0.0-20230315142452-642cacee5cc0 // indirect
+	golang.org/x/sys v0.30.0 // indirect
 	gopkg.in/alexcesaro/quotedprintable.v3 v3.0.0-20150716171945-2caba252f4dc // indirect
+	modernc.org/libc v1.61.13 // indirect
+	modernc.org/mathutil v1.7.1 // indirect
+	modernc.org/memory v1.8.2 // indirect
 )
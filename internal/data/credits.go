@@ -0,0 +1,44 @@
+package data
+
+import (
+	"context"
+	"errors"
+
+	"github.com/arian-nj/site/back/internal/validator"
+)
+
+const (
+	RoleDirector = "director"
+	RoleActor    = "actor"
+	RoleWriter   = "writer"
+)
+
+var ErrDuplicateCredit = errors.New("duplicate credit")
+
+// Credit links a person to a movie in a given role. The movie and person
+// names are filled in when reading, so a credit can be shown from either
+// side without another lookup.
+type Credit struct {
+	ID         int64  `json:"id"`
+	MovieID    int64  `json:"movie_id"`
+	MovieTitle string `json:"movie_title,omitempty"`
+	MovieYear  int32  `json:"movie_year,omitempty"`
+	PersonID   int64  `json:"person_id"`
+	PersonName string `json:"person_name,omitempty"`
+	Role       string `json:"role"`
+	Character  string `json:"character,omitempty"`
+}
+
+type CreditRepository interface {
+	Insert(ctx context.Context, credit *Credit) error
+	Delete(ctx context.Context, movieID, creditID int64) error
+	GetAllForMovie(ctx context.Context, movieID int64) ([]*Credit, error)
+	GetAllForPerson(ctx context.Context, personID int64) ([]*Credit, error)
+}
+
+func ValidateCredit(v *validator.Validator, credit *Credit) {
+	v.Check(credit.PersonID > 0, "person_id", "must be provided")
+	v.Check(validator.In(credit.Role, RoleDirector, RoleActor, RoleWriter), "role", "must be director, actor or writer")
+	v.Check(credit.Character == "" || credit.Role == RoleActor, "character", "must only be set for actors")
+	v.Check(len(credit.Character) <= 500, "character", "must not be more than 500 bytes long")
+}
@@ -0,0 +1,100 @@
+package data
+
+import (
+	"cmp"
+	"context"
+	"slices"
+)
+
+// creditRoleOrder lists roles in the order GetAllForMovie() returns them.
+var creditRoleOrder = []string{RoleDirector, RoleWriter, RoleActor}
+
+type creditMemModel struct {
+	s *memoryStore
+}
+
+func (m creditMemModel) Insert(ctx context.Context, credit *Credit) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	_, movieExists := m.s.movies[credit.MovieID]
+	_, personExists := m.s.people[credit.PersonID]
+	if !movieExists || !personExists {
+		return ErrRecordNotFound
+	}
+	for _, other := range m.s.credits {
+		if other.MovieID == credit.MovieID && other.PersonID == credit.PersonID &&
+			other.Role == credit.Role && other.Character == credit.Character {
+			return ErrDuplicateCredit
+		}
+	}
+	credit.ID = m.s.nextID("credits")
+	m.s.credits[credit.ID] = &Credit{
+		ID:        credit.ID,
+		MovieID:   credit.MovieID,
+		PersonID:  credit.PersonID,
+		Role:      credit.Role,
+		Character: credit.Character,
+	}
+	return nil
+}
+
+func (m creditMemModel) Delete(ctx context.Context, movieID, creditID int64) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	credit, ok := m.s.credits[creditID]
+	if !ok || credit.MovieID != movieID {
+		return ErrRecordNotFound
+	}
+	delete(m.s.credits, creditID)
+	return nil
+}
+
+// creditsWhere() returns the credits accepted by keep with the movie and person
+// details filled in.
+func (s *memoryStore) creditsWhere(keep func(credit *Credit, movie *Movie) bool) []*Credit {
+	credits := []*Credit{}
+	for _, stored := range s.credits {
+		movie, person := s.movies[stored.MovieID], s.people[stored.PersonID]
+		if !keep(stored, movie) {
+			continue
+		}
+		credit := *stored
+		credit.MovieTitle, credit.MovieYear = movie.Title, movie.Year
+		credit.PersonName = person.Name
+		credits = append(credits, &credit)
+	}
+	return credits
+}
+
+func (m creditMemModel) GetAllForMovie(ctx context.Context, movieID int64) ([]*Credit, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	credits := m.s.creditsWhere(func(credit *Credit, _ *Movie) bool {
+		return credit.MovieID == movieID
+	})
+	slices.SortFunc(credits, func(a, b *Credit) int {
+		if c := cmp.Compare(slices.Index(creditRoleOrder, a.Role), slices.Index(creditRoleOrder, b.Role)); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return credits, nil
+}
+
+func (m creditMemModel) GetAllForPerson(ctx context.Context, personID int64) ([]*Credit, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	credits := m.s.creditsWhere(func(credit *Credit, movie *Movie) bool {
+		return credit.PersonID == personID && movie.DeletedAt == nil
+	})
+	slices.SortFunc(credits, func(a, b *Credit) int {
+		if c := cmp.Compare(b.MovieYear, a.MovieYear); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(a.MovieID, b.MovieID); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return credits, nil
+}
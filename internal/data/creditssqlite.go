@@ -0,0 +1,113 @@
+package data
+
+import (
+	"context"
+	"time"
+)
+
+// creditSQLiteModel is the SQLite CreditRepository.
+type creditSQLiteModel struct {
+	DB      DBTX
+	Timeout time.Duration
+}
+
+func (m creditSQLiteModel) Insert(ctx context.Context, credit *Credit) error {
+	query := `
+	INSERT INTO credits (movie_id, person_id, role, character)
+	VALUES ($1, $2, $3, $4)
+	RETURNING id`
+
+	ctx, cancel := context.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+	err := m.DB.QueryRowContext(ctx, query, credit.MovieID, credit.PersonID, credit.Role, credit.Character).
+		Scan(&credit.ID)
+	if err != nil {
+		switch {
+		case isUniqueViolation(err):
+			return ErrDuplicateCredit
+		case isForeignKeyViolation(err):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+func (m creditSQLiteModel) Delete(ctx context.Context, movieID, creditID int64) error {
+	query := `
+	DELETE FROM credits
+	WHERE id = $1 AND movie_id = $2`
+
+	ctx, cancel := context.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+	result, err := m.DB.ExecContext(ctx, query, creditID, movieID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// GetAllForMovie() returns the cast and crew of a movie, directors first.
+func (m creditSQLiteModel) GetAllForMovie(ctx context.Context, movieID int64) ([]*Credit, error) {
+	return m.query(ctx, `
+	SELECT credits.id, movies.id, movies.title, movies.year, people.id, people.name,
+	credits.role, credits.character
+	FROM credits
+	INNER JOIN movies ON movies.id = credits.movie_id
+	INNER JOIN people ON people.id = credits.person_id
+	WHERE credits.movie_id = $1
+	ORDER BY CASE credits.role WHEN 'director' THEN 1 WHEN 'writer' THEN 2 ELSE 3 END, credits.id`, movieID)
+}
+
+// GetAllForPerson() returns a person's filmography, newest movies first.
+func (m creditSQLiteModel) GetAllForPerson(ctx context.Context, personID int64) ([]*Credit, error) {
+	return m.query(ctx, `
+	SELECT credits.id, movies.id, movies.title, movies.year, people.id, people.name,
+	credits.role, credits.character
+	FROM credits
+	INNER JOIN movies ON movies.id = credits.movie_id
+	INNER JOIN people ON people.id = credits.person_id
+	WHERE credits.person_id = $1 AND movies.deleted_at IS NULL
+	ORDER BY movies.year DESC, movies.id, credits.id`, personID)
+}
+
+func (m creditSQLiteModel) query(ctx context.Context, query string, args ...interface{}) ([]*Credit, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	credits := []*Credit{}
+	for rows.Next() {
+		var credit Credit
+		err := rows.Scan(
+			&credit.ID,
+			&credit.MovieID,
+			&credit.MovieTitle,
+			&credit.MovieYear,
+			&credit.PersonID,
+			&credit.PersonName,
+			&credit.Role,
+			&credit.Character,
+		)
+		if err != nil {
+			return nil, err
+		}
+		credits = append(credits, &credit)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return credits, nil
+}
@@ -1,18 +1,24 @@
 package data
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"math"
+	"strconv"
 	"strings"
 
 	"github.com/arian-nj/site/back/internal/validator"
 )
 
 type Metadata struct {
-	CurrentPage  int `json:"current_page,omitempty"`
-	PageSize     int `json:"page_size,omitempty"`
-	FirstPage    int `json:"first_page,omitempty"`
-	LastPage     int `json:"last_page,omitempty"`
-	TotalRecords int `json:"total_records,omitempty"`
+	CurrentPage  int    `json:"current_page,omitempty"`
+	PageSize     int    `json:"page_size,omitempty"`
+	FirstPage    int    `json:"first_page,omitempty"`
+	LastPage     int    `json:"last_page,omitempty"`
+	TotalRecords int    `json:"total_records,omitempty"`
+	NextCursor   string `json:"next_cursor,omitempty"`
+	PrevCursor   string `json:"prev_cursor,omitempty"`
 }
 
 func calculateMetadata(totalRecords, page, pageSize int) Metadata {
@@ -31,6 +37,7 @@ type Filters struct {
 	PageSize     int
 	Sort         string
 	SortSafelist []string
+	Cursor       string
 }
 
 func ValidateFilter(v *validator.Validator, f Filters) {
@@ -50,9 +57,95 @@ func (f *Filters) sortColumn() string {
 	panic("unsafe sort parameter: " + f.Sort)
 }
 
+// sortDirection() returns the SQL direction of the sort. relevance is the
+// exception to "-" meaning descending: sort=relevance lists the best matches
+// first and -relevance the weakest.
 func (f *Filters) sortDirection() string {
-	if strings.HasPrefix(f.Sort, "-") {
+	desc := strings.HasPrefix(f.Sort, "-")
+	if strings.TrimPrefix(f.Sort, "-") == "relevance" {
+		desc = !desc
+	}
+	if desc {
 		return "DESC"
 	}
 	return "ASC"
 }
+
+// ErrInvalidCursor is returned for a cursor that is malformed or was not
+// handed out for the requested sort.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// cursor marks a position in a keyset-paginated listing: the sort key and id
+// of the row next to the page boundary. Prev is set when the cursor points
+// back towards the start of the listing.
+type cursor struct {
+	Sort string `json:"s"`
+	Key  string `json:"k"`
+	ID   int64  `json:"i"`
+	Prev bool   `json:"p,omitempty"`
+}
+
+func (c cursor) encode() string {
+	js, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(js)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+	js, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(js, &c); err != nil || c.ID < 1 {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// decodeMovieCursor() decodes the cursor of a movie listing. It must have
+// been handed out for the same sort and its key must parse as the type of
+// the sort column, so a tampered cursor is rejected with ErrInvalidCursor
+// instead of failing in the database. The returned movie holds the sort key
+// and id of the cursor position.
+func decodeMovieCursor(filter Filters) (cursor, *Movie, error) {
+	c, err := decodeCursor(filter.Cursor)
+	if err != nil {
+		return c, nil, err
+	}
+	if c.Sort != filter.Sort {
+		return c, nil, ErrInvalidCursor
+	}
+	movie := &Movie{ID: c.ID}
+	var n int64
+	switch filter.sortColumn() {
+	case "id":
+		_, err = strconv.ParseInt(c.Key, 10, 64)
+	case "title":
+		movie.Title = c.Key
+	case "year":
+		n, err = strconv.ParseInt(c.Key, 10, 32)
+		movie.Year = int32(n)
+	case "runtime":
+		n, err = strconv.ParseInt(c.Key, 10, 32)
+		movie.Runtime = Runtime(n)
+	case "average_rating":
+		movie.AverageRating, err = parseFiniteFloat(c.Key)
+	case "rating_count":
+		n, err = strconv.ParseInt(c.Key, 10, 32)
+		movie.RatingCount = int32(n)
+	case "relevance":
+		movie.Relevance, err = parseFiniteFloat(c.Key)
+	}
+	if err != nil {
+		return c, nil, ErrInvalidCursor
+	}
+	return c, movie, nil
+}
+
+func parseFiniteFloat(s string) (float64, error) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err == nil && (math.IsNaN(f) || math.IsInf(f, 0)) {
+		return 0, ErrInvalidCursor
+	}
+	return f, err
+}
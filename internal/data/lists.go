@@ -0,0 +1,65 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/arian-nj/site/back/internal/validator"
+)
+
+const (
+	VisibilityPublic  = "public"
+	VisibilityPrivate = "private"
+
+	// DefaultListName is the name of the list every user gets for free.
+	DefaultListName = "watchlist"
+)
+
+var (
+	ErrDuplicateListName = errors.New("duplicate list name")
+	ErrDuplicateListItem = errors.New("duplicate list item")
+)
+
+type List struct {
+	ID         int64     `json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	UserID     int64     `json:"user_id"`
+	Name       string    `json:"name"`
+	Visibility string    `json:"visibility"`
+	IsDefault  bool      `json:"is_default"`
+	Version    int32     `json:"version"`
+}
+
+// ListItem is a movie's entry in a list. Positions start at 1 and order the
+// list, but they may have gaps: purging a movie drops its entries, and movies
+// in the trash keep their positions while hidden from the list.
+type ListItem struct {
+	Position int       `json:"position"`
+	AddedAt  time.Time `json:"added_at"`
+	Movie    *Movie    `json:"movie"`
+}
+
+type ListRepository interface {
+	Insert(ctx context.Context, list *List) error
+	Get(ctx context.Context, id int64) (*List, error)
+	GetDefault(ctx context.Context, userID int64) (*List, error)
+	GetAllForUser(ctx context.Context, userID int64) ([]*List, error)
+	Update(ctx context.Context, list *List) error
+	Delete(ctx context.Context, id int64) error
+	AddMovie(ctx context.Context, listID, movieID int64) (*ListItem, error)
+	RemoveMovie(ctx context.Context, listID, movieID int64) error
+	MoveMovie(ctx context.Context, listID, movieID int64, position int) (*ListItem, error)
+	GetMovies(ctx context.Context, listID int64, filter Filters) ([]*ListItem, Metadata, error)
+}
+
+func (l *List) IsPublic() bool {
+	return l.Visibility == VisibilityPublic
+}
+
+func ValidateList(v *validator.Validator, list *List) {
+	v.Check(list.Name != "", "name", "must be provided")
+	v.Check(len(list.Name) <= 200, "name", "must not be more than 200 bytes long")
+	v.Check(list.IsDefault || list.Name != DefaultListName, "name", "is reserved for the default list")
+	v.Check(validator.In(list.Visibility, VisibilityPublic, VisibilityPrivate), "visibility", "must be public or private")
+}
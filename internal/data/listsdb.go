@@ -0,0 +1,339 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type ListModel struct {
+	DB      DBTX
+	Timeout time.Duration
+}
+
+func (m ListModel) Insert(ctx context.Context, list *List) error {
+	query := `
+	INSERT INTO lists (user_id, name, visibility)
+	VALUES ($1, $2, $3)
+	RETURNING id, created_at, is_default, version`
+
+	ctx, cancel := context.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+	err := m.DB.QueryRowContext(ctx, query, list.UserID, list.Name, list.Visibility).
+		Scan(&list.ID, &list.CreatedAt, &list.IsDefault, &list.Version)
+	if isUniqueViolation(err) {
+		return ErrDuplicateListName
+	}
+	return err
+}
+
+func (m ListModel) Get(ctx context.Context, id int64) (*List, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+	query := `
+	SELECT id, created_at, user_id, name, visibility, is_default, version
+	FROM lists
+	WHERE id = $1`
+	return m.get(ctx, query, id)
+}
+
+// GetDefault() returns the user's watchlist, creating it on first use.
+func (m ListModel) GetDefault(ctx context.Context, userID int64) (*List, error) {
+	insert := `
+	INSERT INTO lists (user_id, name, is_default)
+	VALUES ($1, $2, true)
+	ON CONFLICT DO NOTHING`
+
+	ctx, cancel := context.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+	_, err := m.DB.ExecContext(ctx, insert, userID, DefaultListName)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+	SELECT id, created_at, user_id, name, visibility, is_default, version
+	FROM lists
+	WHERE user_id = $1 AND is_default`
+	return m.get(ctx, query, userID)
+}
+
+func (m ListModel) get(ctx context.Context, query string, arg interface{}) (*List, error) {
+	var list List
+	ctx, cancel := context.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+	err := m.DB.QueryRowContext(ctx, query, arg).Scan(
+		&list.ID,
+		&list.CreatedAt,
+		&list.UserID,
+		&list.Name,
+		&list.Visibility,
+		&list.IsDefault,
+		&list.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &list, nil
+}
+
+// GetAllForUser() returns the user's lists, watchlist first.
+func (m ListModel) GetAllForUser(ctx context.Context, userID int64) ([]*List, error) {
+	query := `
+	SELECT id, created_at, user_id, name, visibility, is_default, version
+	FROM lists
+	WHERE user_id = $1
+	ORDER BY is_default DESC, name ASC`
+
+	ctx, cancel := context.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lists := []*List{}
+	for rows.Next() {
+		var list List
+		err := rows.Scan(
+			&list.ID,
+			&list.CreatedAt,
+			&list.UserID,
+			&list.Name,
+			&list.Visibility,
+			&list.IsDefault,
+			&list.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, &list)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return lists, nil
+}
+
+func (m ListModel) Update(ctx context.Context, list *List) error {
+	query := `
+	UPDATE lists
+	SET name = $1, visibility = $2, version = version + 1
+	WHERE id = $3 AND version = $4
+	RETURNING version`
+
+	ctx, cancel := context.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+	err := m.DB.QueryRowContext(ctx, query, list.Name, list.Visibility, list.ID, list.Version).Scan(&list.Version)
+	if err != nil {
+		switch {
+		case isUniqueViolation(err):
+			return ErrDuplicateListName
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+func (m ListModel) Delete(ctx context.Context, id int64) error {
+	query := `
+	DELETE FROM lists
+	WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// AddMovie() appends a movie to the end of a list.
+func (m ListModel) AddMovie(ctx context.Context, listID, movieID int64) (*ListItem, error) {
+	query := `
+	INSERT INTO list_items (list_id, movie_id, position)
+	SELECT $1, $2, COALESCE(max(position), 0) + 1
+	FROM list_items
+	WHERE list_id = $1
+	HAVING EXISTS (SELECT 1 FROM movies WHERE id = $2 AND deleted_at IS NULL)
+	RETURNING position, added_at`
+
+	item := &ListItem{}
+	err := m.withLock(ctx, listID, func(ctx context.Context, tx *sql.Tx) error {
+		return tx.QueryRowContext(ctx, query, listID, movieID).Scan(&item.Position, &item.AddedAt)
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		case isUniqueViolation(err):
+			return nil, ErrDuplicateListItem
+		case isForeignKeyViolation(err):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return item, nil
+}
+
+// RemoveMovie() removes a movie from a list and closes the gap it leaves.
+func (m ListModel) RemoveMovie(ctx context.Context, listID, movieID int64) error {
+	return m.withLock(ctx, listID, func(ctx context.Context, tx *sql.Tx) error {
+		var position int
+		err := tx.QueryRowContext(ctx, `
+		DELETE FROM list_items
+		WHERE list_id = $1 AND movie_id = $2
+		RETURNING position`, listID, movieID).Scan(&position)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrRecordNotFound
+			}
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `
+		UPDATE list_items
+		SET position = position - 1
+		WHERE list_id = $1 AND position > $2`, listID, position)
+		return err
+	})
+}
+
+// MoveMovie() moves a movie to position, shifting the movies in between.
+// Positions past the last one move the movie to the end.
+func (m ListModel) MoveMovie(ctx context.Context, listID, movieID int64, position int) (*ListItem, error) {
+	item := &ListItem{}
+	err := m.withLock(ctx, listID, func(ctx context.Context, tx *sql.Tx) error {
+		var current, last int
+		err := tx.QueryRowContext(ctx, `
+		SELECT position, (SELECT max(position) FROM list_items WHERE list_id = $1)
+		FROM list_items
+		WHERE list_id = $1 AND movie_id = $2`, listID, movieID).Scan(&current, &last)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrRecordNotFound
+			}
+			return err
+		}
+		position = min(max(position, 1), last)
+
+		switch {
+		case position < current:
+			_, err = tx.ExecContext(ctx, `
+			UPDATE list_items SET position = position + 1
+			WHERE list_id = $1 AND position >= $2 AND position < $3`, listID, position, current)
+		case position > current:
+			_, err = tx.ExecContext(ctx, `
+			UPDATE list_items SET position = position - 1
+			WHERE list_id = $1 AND position > $2 AND position <= $3`, listID, current, position)
+		}
+		if err != nil {
+			return err
+		}
+
+		return tx.QueryRowContext(ctx, `
+		UPDATE list_items SET position = $3
+		WHERE list_id = $1 AND movie_id = $2
+		RETURNING position, added_at`, listID, movieID, position).Scan(&item.Position, &item.AddedAt)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+func (m ListModel) GetMovies(ctx context.Context, listID int64, filter Filters) ([]*ListItem, Metadata, error) {
+	query := fmt.Sprintf(`
+	SELECT count(*) OVER(), list_items.position, list_items.added_at,
+	movies.id, movies.created_at, movies.title, movies.year, movies.runtime, movies.genres,
+	movies.version, movies.average_rating, movies.rating_count
+	FROM list_items
+	INNER JOIN movies ON movies.id = list_items.movie_id
+	WHERE list_items.list_id = $1 AND movies.deleted_at IS NULL
+	ORDER BY %s %s, list_items.position ASC LIMIT $2 OFFSET $3`, filter.sortColumn(), filter.sortDirection())
+
+	ctx, cancel := context.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+	rows, err := m.DB.QueryContext(ctx, query, listID, filter.PageSize, (filter.Page-1)*filter.PageSize)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	items := []*ListItem{}
+	for rows.Next() {
+		var item ListItem
+		var movie Movie
+		err := rows.Scan(
+			&totalRecords,
+			&item.Position,
+			&item.AddedAt,
+			&movie.ID,
+			&movie.CreatedAt,
+			&movie.Title,
+			&movie.Year,
+			&movie.Runtime,
+			pq.Array(&movie.Genres),
+			&movie.Version,
+			&movie.AverageRating,
+			&movie.RatingCount,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		item.Movie = &movie
+		items = append(items, &item)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+	return items, calculateMetadata(totalRecords, filter.Page, filter.PageSize), nil
+}
+
+// withLock() runs fn in a transaction holding a row lock on the list, so
+// concurrent changes to the same list keep positions consistent.
+func (m ListModel) withLock(ctx context.Context, listID int64, fn func(ctx context.Context, tx *sql.Tx) error) error {
+	ctx, cancel := context.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+	tx, err := beginTx(ctx, m.DB, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var id int64
+	err = tx.QueryRowContext(ctx, `SELECT id FROM lists WHERE id = $1 FOR UPDATE`, listID).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrRecordNotFound
+		}
+		return err
+	}
+	if err := fn(ctx, tx.Tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,220 @@
+package data
+
+import (
+	"cmp"
+	"context"
+	"slices"
+)
+
+// listMemModel stores list items by list and movie id. Positions are kept
+// explicitly and renumbered the same way the SQL statements do, so the
+// two backends leave identical gaps when a movie is purged.
+type listMemModel struct {
+	s *memoryStore
+}
+
+func cloneList(list *List) *List {
+	clone := *list
+	return &clone
+}
+
+func (s *memoryStore) listNameTaken(userID int64, name string, exceptID int64) bool {
+	for _, list := range s.lists {
+		if list.ID != exceptID && list.UserID == userID && list.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *memoryStore) insertList(list *List) {
+	list.ID = s.nextID("lists")
+	list.CreatedAt = now()
+	list.Version = 1
+	s.lists[list.ID] = cloneList(list)
+	s.listItems[list.ID] = make(map[int64]*ListItem)
+}
+
+func (m listMemModel) Insert(ctx context.Context, list *List) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	if _, ok := m.s.users[list.UserID]; !ok {
+		return ErrRecordNotFound
+	}
+	if m.s.listNameTaken(list.UserID, list.Name, 0) {
+		return ErrDuplicateListName
+	}
+	list.IsDefault = false
+	m.s.insertList(list)
+	return nil
+}
+
+func (m listMemModel) Get(ctx context.Context, id int64) (*List, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	list, ok := m.s.lists[id]
+	if !ok {
+		return nil, ErrRecordNotFound
+	}
+	return cloneList(list), nil
+}
+
+func (m listMemModel) GetDefault(ctx context.Context, userID int64) (*List, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	for _, list := range m.s.lists {
+		if list.UserID == userID && list.IsDefault {
+			return cloneList(list), nil
+		}
+	}
+	if _, ok := m.s.users[userID]; !ok {
+		return nil, ErrRecordNotFound
+	}
+	list := &List{UserID: userID, Name: DefaultListName, Visibility: VisibilityPrivate, IsDefault: true}
+	m.s.insertList(list)
+	return list, nil
+}
+
+func (m listMemModel) GetAllForUser(ctx context.Context, userID int64) ([]*List, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	lists := []*List{}
+	for _, list := range m.s.lists {
+		if list.UserID == userID {
+			lists = append(lists, cloneList(list))
+		}
+	}
+	slices.SortFunc(lists, func(a, b *List) int {
+		if a.IsDefault != b.IsDefault {
+			if a.IsDefault {
+				return -1
+			}
+			return 1
+		}
+		return cmp.Compare(a.Name, b.Name)
+	})
+	return lists, nil
+}
+
+func (m listMemModel) Update(ctx context.Context, list *List) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	stored, ok := m.s.lists[list.ID]
+	if !ok || stored.Version != list.Version {
+		return ErrEditConflict
+	}
+	if m.s.listNameTaken(stored.UserID, list.Name, list.ID) {
+		return ErrDuplicateListName
+	}
+	stored.Name = list.Name
+	stored.Visibility = list.Visibility
+	stored.Version++
+	list.Version = stored.Version
+	return nil
+}
+
+func (m listMemModel) Delete(ctx context.Context, id int64) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	if _, ok := m.s.lists[id]; !ok {
+		return ErrRecordNotFound
+	}
+	delete(m.s.lists, id)
+	delete(m.s.listItems, id)
+	return nil
+}
+
+func (m listMemModel) AddMovie(ctx context.Context, listID, movieID int64) (*ListItem, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	items, ok := m.s.listItems[listID]
+	if !ok {
+		return nil, ErrRecordNotFound
+	}
+	if _, ok := m.s.live(movieID); !ok {
+		return nil, ErrRecordNotFound
+	}
+	if _, ok := items[movieID]; ok {
+		return nil, ErrDuplicateListItem
+	}
+	position := 0
+	for _, item := range items {
+		position = max(position, item.Position)
+	}
+	item := &ListItem{Position: position + 1, AddedAt: now()}
+	items[movieID] = item
+	return &ListItem{Position: item.Position, AddedAt: item.AddedAt}, nil
+}
+
+func (m listMemModel) RemoveMovie(ctx context.Context, listID, movieID int64) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	items, ok := m.s.listItems[listID]
+	if !ok {
+		return ErrRecordNotFound
+	}
+	removed, ok := items[movieID]
+	if !ok {
+		return ErrRecordNotFound
+	}
+	delete(items, movieID)
+	for _, item := range items {
+		if item.Position > removed.Position {
+			item.Position--
+		}
+	}
+	return nil
+}
+
+func (m listMemModel) MoveMovie(ctx context.Context, listID, movieID int64, position int) (*ListItem, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	items, ok := m.s.listItems[listID]
+	if !ok {
+		return nil, ErrRecordNotFound
+	}
+	moved, ok := items[movieID]
+	if !ok {
+		return nil, ErrRecordNotFound
+	}
+	current, last := moved.Position, 0
+	for _, item := range items {
+		last = max(last, item.Position)
+	}
+	position = min(max(position, 1), last)
+	for _, item := range items {
+		switch {
+		case position < current && item.Position >= position && item.Position < current:
+			item.Position++
+		case position > current && item.Position > current && item.Position <= position:
+			item.Position--
+		}
+	}
+	moved.Position = position
+	return &ListItem{Position: moved.Position, AddedAt: moved.AddedAt}, nil
+}
+
+func (m listMemModel) GetMovies(ctx context.Context, listID int64, filter Filters) ([]*ListItem, Metadata, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	items := []*ListItem{}
+	for movieID, stored := range m.s.listItems[listID] {
+		movie, ok := m.s.live(movieID)
+		if !ok {
+			continue
+		}
+		items = append(items, &ListItem{Position: stored.Position, AddedAt: stored.AddedAt, Movie: cloneMovie(movie)})
+	}
+
+	byPosition := func(a, b *ListItem) int { return cmp.Compare(a.Position, b.Position) }
+	column := byPosition
+	switch filter.sortColumn() {
+	case "added_at":
+		column = func(a, b *ListItem) int { return a.AddedAt.Compare(b.AddedAt) }
+	case "title":
+		column = func(a, b *ListItem) int { return cmp.Compare(a.Movie.Title, b.Movie.Title) }
+	}
+	slices.SortFunc(items, orderBy(filter, column, byPosition))
+	items, metadata := paginate(items, filter)
+	return items, metadata, nil
+}
@@ -0,0 +1,220 @@
+package data
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// memoryStore holds every table of the in-memory backend. A single lock
+// guards all of them, so operations spanning several tables (like the
+// cascades of Purge()) are atomic just as they are in a transaction.
+type memoryStore struct {
+	mu sync.Locker
+	*memoryTables
+}
+
+type memoryTables struct {
+	lastID map[string]int64
+
+	movies      map[int64]*Movie
+	revisions   map[int64][]*Revision
+	users       map[int64]*User
+	tokens      map[string]*Token
+	permissions map[int64]Permissions
+	reviews     map[memReviewKey]*Review
+	people      map[int64]*Person
+	credits     map[int64]*Credit
+	lists       map[int64]*List
+	listItems   map[int64]map[int64]*ListItem
+}
+
+type memReviewKey struct {
+	userID, movieID int64
+}
+
+// NewMemoryModels() returns Models backed by process memory. Nothing is
+// persisted, which makes it suitable for demos and tests that shouldn't
+// need a running database.
+func NewMemoryModels() *Models {
+	s := &memoryStore{
+		mu: &sync.Mutex{},
+		memoryTables: &memoryTables{
+			lastID:      make(map[string]int64),
+			movies:      make(map[int64]*Movie),
+			revisions:   make(map[int64][]*Revision),
+			users:       make(map[int64]*User),
+			tokens:      make(map[string]*Token),
+			permissions: make(map[int64]Permissions),
+			reviews:     make(map[memReviewKey]*Review),
+			people:      make(map[int64]*Person),
+			credits:     make(map[int64]*Credit),
+			lists:       make(map[int64]*List),
+			listItems:   make(map[int64]map[int64]*ListItem),
+		},
+	}
+	models := newMemModels(s)
+	models.withTx = s.withTx
+	return models
+}
+
+func newMemModels(s *memoryStore) *Models {
+	return &Models{
+		Movie:       movieMemModel{s},
+		Users:       userMemModel{s},
+		Token:       tokenMemModel{s},
+		Permissions: permissionMemModel{s},
+		Revisions:   revisionMemModel{s},
+		Reviews:     reviewMemModel{s},
+		People:      personMemModel{s},
+		Credits:     creditMemModel{s},
+		Lists:       listMemModel{s},
+	}
+}
+
+// withTx() holds the store lock for the whole unit of work and hands fn
+// models that skip locking. The tables are snapshotted first and put back
+// if fn fails.
+func (s *memoryStore) withTx(_ context.Context, fn func(tx *Models) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot := s.memoryTables.clone()
+	tx := newMemModels(&memoryStore{mu: nopLocker{}, memoryTables: s.memoryTables})
+	tx.withTx = func(_ context.Context, fn func(tx *Models) error) error {
+		return fn(tx)
+	}
+	if err := fn(tx); err != nil {
+		*s.memoryTables = *snapshot
+		return err
+	}
+	return nil
+}
+
+// nopLocker is the lock of the store seen from inside withTx(), where the
+// real lock is already held.
+type nopLocker struct{}
+
+func (nopLocker) Lock()   {}
+func (nopLocker) Unlock() {}
+
+// clone() returns a deep copy of the tables.
+func (t *memoryTables) clone() *memoryTables {
+	c := &memoryTables{
+		lastID:      maps.Clone(t.lastID),
+		movies:      make(map[int64]*Movie, len(t.movies)),
+		revisions:   make(map[int64][]*Revision, len(t.revisions)),
+		users:       make(map[int64]*User, len(t.users)),
+		tokens:      make(map[string]*Token, len(t.tokens)),
+		permissions: make(map[int64]Permissions, len(t.permissions)),
+		reviews:     make(map[memReviewKey]*Review, len(t.reviews)),
+		people:      make(map[int64]*Person, len(t.people)),
+		credits:     make(map[int64]*Credit, len(t.credits)),
+		lists:       make(map[int64]*List, len(t.lists)),
+		listItems:   make(map[int64]map[int64]*ListItem, len(t.listItems)),
+	}
+	for id, movie := range t.movies {
+		clone := cloneMovie(movie)
+		if movie.DeletedAt != nil {
+			deletedAt := *movie.DeletedAt
+			clone.DeletedAt = &deletedAt
+		}
+		c.movies[id] = clone
+	}
+	for id, revisions := range t.revisions {
+		for _, revision := range revisions {
+			c.revisions[id] = append(c.revisions[id], cloneRevision(revision))
+		}
+	}
+	for id, user := range t.users {
+		c.users[id] = cloneUser(user)
+	}
+	for hash, token := range t.tokens {
+		clone := *token
+		c.tokens[hash] = &clone
+	}
+	for id, permissions := range t.permissions {
+		c.permissions[id] = slices.Clone(permissions)
+	}
+	for key, review := range t.reviews {
+		clone := *review
+		c.reviews[key] = &clone
+	}
+	for id, person := range t.people {
+		c.people[id] = clonePerson(person)
+	}
+	for id, credit := range t.credits {
+		clone := *credit
+		c.credits[id] = &clone
+	}
+	for id, list := range t.lists {
+		c.lists[id] = cloneList(list)
+	}
+	for id, items := range t.listItems {
+		c.listItems[id] = make(map[int64]*ListItem, len(items))
+		for movieID, item := range items {
+			clone := *item
+			c.listItems[id][movieID] = &clone
+		}
+	}
+	return c
+}
+
+// nextID() plays the part of a bigserial column.
+func (s *memoryStore) nextID(table string) int64 {
+	s.lastID[table]++
+	return s.lastID[table]
+}
+
+// now() returns the current time at the precision of the timestamp(0)
+// columns used by PostgreSQL.
+func now() time.Time {
+	return time.Now().Truncate(time.Second)
+}
+
+// orderBy() returns a comparison matching "ORDER BY column direction,
+// tiebreak ASC", where column compares the sort column of filter.
+func orderBy[T any](filter Filters, column func(a, b T) int, tiebreak func(a, b T) int) func(a, b T) int {
+	desc := filter.sortDirection() == "DESC"
+	return func(a, b T) int {
+		c := column(a, b)
+		if desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+		return tiebreak(a, b)
+	}
+}
+
+// paginate() returns the page of rows selected by filter together with its
+// metadata, like "LIMIT page_size OFFSET ..." with count(*) OVER().
+// An empty page reports no records, as the window count has no row to
+// appear on.
+func paginate[T any](rows []T, filter Filters) ([]T, Metadata) {
+	offset := min((filter.Page-1)*filter.PageSize, len(rows))
+	end := min(offset+filter.PageSize, len(rows))
+	page := rows[offset:end]
+	if len(page) == 0 {
+		return page, calculateMetadata(0, filter.Page, filter.PageSize)
+	}
+	return page, calculateMetadata(len(rows), filter.Page, filter.PageSize)
+}
+
+// compareNullable() compares optional values with nil sorting after every
+// value, as NULL does in an ascending PostgreSQL sort.
+func compareNullable[T cmp.Ordered](a, b *T) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+	return cmp.Compare(*a, *b)
+}
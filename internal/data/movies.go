@@ -1,6 +1,10 @@
 package data
 
 import (
+	"context"
+	"slices"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/arian-nj/site/back/internal/validator"
@@ -14,6 +18,134 @@ type Movie struct {
 	Runtime   Runtime   `json:"runtime"  db:"runtime"`
 	Genres    []string  `json:"genres"  db:"genres"`
 	Version   int32     `json:"version"  db:"version"`
+	// AverageRating and RatingCount summarise the movie's reviews and are
+	// kept up to date by ReviewModel.
+	AverageRating float64 `json:"average_rating" db:"average_rating"`
+	RatingCount   int32   `json:"rating_count" db:"rating_count"`
+	// Relevance and Highlight are only set when listing with a title search.
+	Relevance float64 `json:"relevance,omitempty" db:"-"`
+	Highlight string  `json:"highlight,omitempty" db:"-"`
+	// DeletedAt is only set when listing the trash.
+	DeletedAt *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
+}
+
+// MovieRepository stores movies. MovieModel keeps them in PostgreSQL and
+// movieMemModel in memory.
+type MovieRepository interface {
+	Insert(ctx context.Context, movie *Movie) error
+	InsertBatch(ctx context.Context, movies []*Movie) error
+	Get(ctx context.Context, id int64) (*Movie, error)
+	Update(ctx context.Context, movie *Movie, editedBy int64) error
+	Delete(ctx context.Context, id int64, version int32) error
+	Restore(ctx context.Context, id int64) (*Movie, error)
+	GetDeleted(ctx context.Context, filter Filters) ([]*Movie, Metadata, error)
+	Purge(ctx context.Context, retention time.Duration) (int64, error)
+	Suggest(ctx context.Context, q string, limit int, threshold float64) ([]*Suggestion, error)
+	GetAll(ctx context.Context, f MovieFilter, filter Filters) ([]*Movie, Metadata, error)
+	Each(ctx context.Context, f MovieFilter, fn func(*Movie) error) error
+	Facets(ctx context.Context, f MovieFilter, names []string) (map[string][]FacetCount, error)
+}
+
+// Suggestion is a title completion returned while the user is typing.
+type Suggestion struct {
+	ID    int64   `json:"id"`
+	Title string  `json:"title"`
+	Year  int32   `json:"year"`
+	Score float64 `json:"score"`
+}
+
+const (
+	FacetGenres        = "genres"
+	FacetDecade        = "decade"
+	FacetRuntimeBucket = "runtime_bucket"
+)
+
+var FacetSafelist = []string{FacetGenres, FacetDecade, FacetRuntimeBucket}
+
+// runtimeBuckets lists the runtime_bucket facet values in display order.
+var runtimeBuckets = []string{"<90", "90-119", "120-149", "150+"}
+
+// FacetCount is the number of matching movies sharing one facet value.
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// sortFacet() orders genres by popularity and the decade and runtime
+// buckets chronologically.
+func sortFacet(name string, counts []FacetCount) {
+	sort.Slice(counts, func(i, j int) bool {
+		switch name {
+		case FacetRuntimeBucket:
+			return slices.Index(runtimeBuckets, counts[i].Value) < slices.Index(runtimeBuckets, counts[j].Value)
+		case FacetDecade:
+			return counts[i].Value < counts[j].Value
+		}
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Value < counts[j].Value
+	})
+}
+
+func ValidateFacets(v *validator.Validator, facets []string) {
+	for _, facet := range facets {
+		v.Check(validator.In(facet, FacetSafelist...), "facets", "invalid facet value")
+	}
+	v.Check(validator.Unique(facets), "facets", "must not contain duplicate values")
+}
+
+// SearchLanguages are the text search configurations a title search may be
+// stemmed with. "simple" does no stemming.
+var SearchLanguages = []string{"simple", "english", "french", "german", "spanish",
+	"italian", "portuguese", "dutch", "russian", "swedish"}
+
+// MovieFilter narrows a movie listing. Zero values match every movie.
+type MovieFilter struct {
+	Title    string
+	Genres   []string
+	PersonID int64
+	Language string
+
+	// GenresAny matches movies with at least one of the genres, while
+	// Genres requires all of them. ExcludeGenres drops movies with any.
+	GenresAny     []string
+	ExcludeGenres []string
+
+	YearMin       int32
+	YearMax       int32
+	RuntimeMin    int32
+	RuntimeMax    int32
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+}
+
+func (f MovieFilter) language() string {
+	if f.Language == "" {
+		return "simple"
+	}
+	return f.Language
+}
+
+func ValidateMovieFilter(v *validator.Validator, f MovieFilter, filters Filters) {
+	v.Check(f.PersonID >= 0, "person", "must be a positive integer")
+	v.Check(validator.In(f.language(), SearchLanguages...), "lang", "unsupported search language")
+	v.Check(f.Title != "" || strings.TrimPrefix(filters.Sort, "-") != "relevance", "sort", "relevance requires a title search")
+
+	v.Check(f.YearMin >= 0, "year_min", "must not be negative")
+	v.Check(f.YearMax >= 0, "year_max", "must not be negative")
+	v.Check(f.YearMax == 0 || f.YearMin <= f.YearMax, "year_min", "must not be greater than year_max")
+	v.Check(f.RuntimeMin >= 0, "runtime_min", "must not be negative")
+	v.Check(f.RuntimeMax >= 0, "runtime_max", "must not be negative")
+	v.Check(f.RuntimeMax == 0 || f.RuntimeMin <= f.RuntimeMax, "runtime_min", "must not be greater than runtime_max")
+	v.Check(f.CreatedBefore.IsZero() || f.CreatedAfter.Before(f.CreatedBefore), "created_after", "must be before created_before")
+
+	v.Check(len(f.Genres) <= 5, "genres", "must not contain more than 5 genres")
+	v.Check(len(f.GenresAny) <= 20, "genres_any", "must not contain more than 20 genres")
+	v.Check(len(f.ExcludeGenres) <= 20, "-genres", "must not contain more than 20 genres")
+	for _, genre := range f.ExcludeGenres {
+		v.Check(!validator.In(genre, f.Genres...), "-genres", "must not exclude a required genre")
+	}
 }
 
 func ValidateMovie(v *validator.Validator, movie *Movie) {
@@ -5,16 +5,18 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/lib/pq"
 )
 
 type MovieModel struct {
-	DB *sql.DB
+	DB      DBTX
+	Timeout time.Duration
 }
 
-// func (s *MovieModel) CreateTable() error {
+// func (s *MovieModel) CreateTable(ctx context.Context) error {
 // 	tx, err := s.db.Begin()
 // 	if err != nil {
 // 		return err
@@ -39,7 +41,7 @@ type MovieModel struct {
 // 	return err
 // }
 
-func (s *MovieModel) Insert(movie *Movie) error {
+func (s *MovieModel) Insert(ctx context.Context, movie *Movie) error {
 	fmt.Println("making ", movie.Title, " in db")
 	statment := `INSERT INTO movies 
 	(title,year,runtime,genres)
@@ -54,19 +56,58 @@ func (s *MovieModel) Insert(movie *Movie) error {
 		pq.Array(movie.Genres),
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	ctx, cancel := context.WithTimeout(ctx, s.Timeout)
 	defer cancel()
 	return s.DB.QueryRowContext(ctx, statment, args...).Scan(&movie.ID, &movie.CreatedAt, &movie.Version)
 }
 
-func (s *MovieModel) Get(id int64) (*Movie, error) {
+// InsertBatch() inserts all movies inside one transaction, so either every
+// movie is created or none are.
+func (s *MovieModel) InsertBatch(ctx context.Context, movies []*Movie) error {
+	statment := `INSERT INTO movies 
+	(title,year,runtime,genres)
+	VALUES 
+	($1,$2,$3,$4) 
+	RETURNING id, created_at, version
+	`
+	ctx, cancel := context.WithTimeout(ctx, batchTimeoutFactor*s.Timeout)
+	defer cancel()
+
+	tx, err := beginTx(ctx, s.DB, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, statment)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, movie := range movies {
+		args := []interface{}{
+			movie.Title,
+			movie.Year,
+			movie.Runtime,
+			pq.Array(movie.Genres),
+		}
+		err = stmt.QueryRowContext(ctx, args...).Scan(&movie.ID, &movie.CreatedAt, &movie.Version)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *MovieModel) Get(ctx context.Context, id int64) (*Movie, error) {
 	if id < 1 {
 		return nil, ErrRecordNotFound
 	}
 	query := `
-	SELECT id, created_at, title, year, runtime, genres, version
+	SELECT id, created_at, title, year, runtime, genres, version, average_rating, rating_count
 	FROM movies
-	WHERE id = $1`
+	WHERE id = $1 AND deleted_at IS NULL`
 	var movie Movie
 
 	args := []interface{}{
@@ -77,9 +118,11 @@ func (s *MovieModel) Get(id int64) (*Movie, error) {
 		&movie.Runtime,
 		pq.Array(&movie.Genres),
 		&movie.Version,
+		&movie.AverageRating,
+		&movie.RatingCount,
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, s.Timeout)
 	defer cancel()
 	err := s.DB.QueryRowContext(ctx, query, id).Scan(args...)
 	if err != nil {
@@ -94,12 +137,21 @@ func (s *MovieModel) Get(id int64) (*Movie, error) {
 	return &movie, nil
 }
 
-func (s *MovieModel) Update(movie *Movie) error {
+// Update() saves movie and archives the row it replaces in movie_revisions,
+// recording editedBy as the user who made the change.
+func (s *MovieModel) Update(ctx context.Context, movie *Movie, editedBy int64) error {
 	query := `
+	WITH previous AS (
+		INSERT INTO movie_revisions (movie_id, version, title, year, runtime, genres, edited_by)
+		SELECT id, version, title, year, runtime, genres, $7
+		FROM movies
+		WHERE id = $5 AND version = $6 AND deleted_at IS NULL
+		ON CONFLICT DO NOTHING
+	)
 	UPDATE movies
 	SET title = $1, year = $2, runtime = $3, genres = $4, version =
 	version + 1
-	WHERE id = $5 AND version=$6
+	WHERE id = $5 AND version=$6 AND deleted_at IS NULL
 	RETURNING version`
 	args := []interface{}{
 		movie.Title,
@@ -108,9 +160,10 @@ func (s *MovieModel) Update(movie *Movie) error {
 		pq.Array(movie.Genres),
 		movie.ID,
 		movie.Version,
+		editedBy,
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	ctx, cancel := context.WithTimeout(ctx, s.Timeout)
 	defer cancel()
 	err := s.DB.QueryRowContext(ctx, query, args...).Scan(&movie.Version)
 	if err != nil {
@@ -122,17 +175,22 @@ func (s *MovieModel) Update(movie *Movie) error {
 	return nil
 }
 
-func (s *MovieModel) Delete(id int64) error {
+// Delete() moves a movie to the trash. It stays restorable until Purge()
+// removes it for good. A non-zero version makes the delete conditional on
+// the movie still being at that version; ErrEditConflict is returned when it
+// has moved on.
+func (s *MovieModel) Delete(ctx context.Context, id int64, version int32) error {
 	if id < 1 {
 		return ErrRecordNotFound
 	}
 	query := `
-	DELETE FROM movies
-	WHERE id = $1`
+	UPDATE movies
+	SET deleted_at = NOW()
+	WHERE id = $1 AND ($2 = 0 OR version = $2) AND deleted_at IS NULL`
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	ctx, cancel := context.WithTimeout(ctx, s.Timeout)
 	defer cancel()
-	result, err := s.DB.ExecContext(ctx, query, id)
+	result, err := s.DB.ExecContext(ctx, query, id, version)
 	if err != nil {
 		return err
 	}
@@ -143,44 +201,70 @@ func (s *MovieModel) Delete(id int64) error {
 	}
 
 	if rowsEffected == 0 {
+		var exists bool
+		err := s.DB.QueryRowContext(ctx,
+			`SELECT EXISTS(SELECT 1 FROM movies WHERE id = $1 AND deleted_at IS NULL)`, id).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return ErrEditConflict
+		}
 		return ErrRecordNotFound
 	}
 
 	return nil
 }
-func (m MovieModel) GetAll(title string, genres []string, filter Filters) ([]*Movie, Metadata, error) {
-	var (
-		LIMIT  = filter.PageSize
-		OFFSET = (filter.Page - 1) * filter.PageSize
-	)
-	query := fmt.Sprintf(`
-	SELECT count(*) OVER(), id, created_at, title, year, runtime, genres, version
-	FROM movies
-	WHERE (to_tsvector('simple', title) @@ plainto_tsquery('simple', $1) OR $1 = '')
-	AND (genres @> $2 OR $2 = '{}')
-	ORDER BY %s %s,id ASC LIMIT $3 OFFSET $4`, filter.sortColumn(), filter.sortDirection())
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
+// Restore() takes a movie back out of the trash.
+func (s *MovieModel) Restore(ctx context.Context, id int64) (*Movie, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+	query := `
+	UPDATE movies
+	SET deleted_at = NULL
+	WHERE id = $1 AND deleted_at IS NOT NULL`
 
-	args := []interface{}{
-		title,
-		pq.Array(genres),
-		LIMIT,
-		OFFSET,
+	ctx, cancel := context.WithTimeout(ctx, s.Timeout)
+	defer cancel()
+	result, err := s.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if rowsAffected == 0 {
+		return nil, ErrRecordNotFound
 	}
+	return s.Get(ctx, id)
+}
 
-	rows, err := m.DB.QueryContext(ctx, query, args...)
+// GetDeleted() lists the movies in the trash, most recently deleted first.
+func (s *MovieModel) GetDeleted(ctx context.Context, filter Filters) ([]*Movie, Metadata, error) {
+	query := `
+	SELECT count(*) OVER(), id, created_at, title, year, runtime, genres, version, average_rating,
+	rating_count, deleted_at
+	FROM movies
+	WHERE deleted_at IS NOT NULL
+	ORDER BY deleted_at DESC, id ASC LIMIT $1 OFFSET $2`
+
+	ctx, cancel := context.WithTimeout(ctx, s.Timeout)
+	defer cancel()
+	rows, err := s.DB.QueryContext(ctx, query, filter.PageSize, (filter.Page-1)*filter.PageSize)
 	if err != nil {
 		return nil, Metadata{}, err
 	}
 	defer rows.Close()
-	totlaRecors := 0
+
+	totalRecords := 0
 	movies := []*Movie{}
 	for rows.Next() {
 		var movie Movie
 		err := rows.Scan(
-			&totlaRecors,
+			&totalRecords,
 			&movie.ID,
 			&movie.CreatedAt,
 			&movie.Title,
@@ -188,6 +272,9 @@ func (m MovieModel) GetAll(title string, genres []string, filter Filters) ([]*Mo
 			&movie.Runtime,
 			pq.Array(&movie.Genres),
 			&movie.Version,
+			&movie.AverageRating,
+			&movie.RatingCount,
+			&movie.DeletedAt,
 		)
 		if err != nil {
 			return nil, Metadata{}, err
@@ -197,6 +284,132 @@ func (m MovieModel) GetAll(title string, genres []string, filter Filters) ([]*Mo
 	if err = rows.Err(); err != nil {
 		return nil, Metadata{}, err
 	}
-	metadata := calculateMetadata(totlaRecors, filter.Page, filter.PageSize)
-	return movies, metadata, nil
+	return movies, calculateMetadata(totalRecords, filter.Page, filter.PageSize), nil
+}
+
+// Purge() permanently deletes movies that have been in the trash for
+// longer than retention, returning how many were removed.
+func (s *MovieModel) Purge(ctx context.Context, retention time.Duration) (int64, error) {
+	query := `
+	DELETE FROM movies
+	WHERE deleted_at < $1`
+
+	ctx, cancel := context.WithTimeout(ctx, purgeTimeoutFactor*s.Timeout)
+	defer cancel()
+	result, err := s.DB.ExecContext(ctx, query, time.Now().Add(-retention))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// Suggest() returns up to limit titles close to q, for search-as-you-type.
+// Titles starting with q rank first; the rest are matched by trigram word
+// similarity, so small typos still find the movie.
+func (m MovieModel) Suggest(ctx context.Context, q string, limit int, threshold float64) ([]*Suggestion, error) {
+	query := `
+	SELECT id, title, year, word_similarity($1, title)
+	FROM movies
+	WHERE ($1 <% title OR title ILIKE $2) AND deleted_at IS NULL
+	ORDER BY title ILIKE $2 DESC, word_similarity($1, title) DESC, title ASC
+	LIMIT $3`
+	prefix := likeEscaper.Replace(q) + "%"
+
+	ctx, cancel := context.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	// The threshold of the <% operator is a setting rather than an argument;
+	// SET LOCAL scoping keeps it from leaking to other pooled connections.
+	tx, err := beginTx(ctx, m.DB, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	_, err = tx.ExecContext(ctx, `SELECT set_config('pg_trgm.word_similarity_threshold', $1, true)`,
+		strconv.FormatFloat(threshold, 'f', -1, 64))
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, query, q, prefix, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	suggestions := []*Suggestion{}
+	for rows.Next() {
+		var suggestion Suggestion
+		err := rows.Scan(&suggestion.ID, &suggestion.Title, &suggestion.Year, &suggestion.Score)
+		if err != nil {
+			return nil, err
+		}
+		suggestions = append(suggestions, &suggestion)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return suggestions, tx.Commit()
+}
+
+// postgresMovies is the PostgreSQL movieDialect.
+var postgresMovies = movieDialect{
+	array:      func(a *[]string) interface{} { return pq.Array(a) },
+	time:       func(t time.Time) interface{} { return t },
+	search:     postgresSearch,
+	genresAll:  func(p string) string { return "movies.genres @> " + p },
+	genresAny:  func(p string) string { return "movies.genres && " + p },
+	genresNone: func(p string) string { return "NOT movies.genres && " + p },
+	facetQueries: map[string]string{
+		FacetGenres: `SELECT 'genres', genre, count(*) FROM matched, unnest(matched.genres) AS genre GROUP BY genre`,
+		FacetDecade: `SELECT 'decade', (year / 10 * 10)::text || 's', count(*) FROM matched GROUP BY 2`,
+		FacetRuntimeBucket: `SELECT 'runtime_bucket', CASE
+		WHEN runtime < 90 THEN '<90'
+		WHEN runtime < 120 THEN '90-119'
+		WHEN runtime < 150 THEN '120-149'
+		ELSE '150+' END, count(*) FROM matched GROUP BY 2`,
+	},
+}
+
+// postgresSearch() adds the title tsquery of f to w and returns the ranking
+// and the highlighted title. Languages come from SearchLanguages, so they
+// are safe to format into the query and the expression indexes can be used.
+// Only "simple" and "english" have an index; the other languages scan every
+// movie.
+func postgresSearch(f MovieFilter, w *sqlWhere) (from, rank, headline string) {
+	if f.Title == "" {
+		return "movies", "0::float8", "''"
+	}
+	lang := f.language()
+	vector := "movies.search_vector"
+	if lang != "simple" {
+		vector = fmt.Sprintf("to_tsvector('%s', movies.title)", lang)
+	}
+	query := fmt.Sprintf("plainto_tsquery('%s', %s)", lang, w.arg(f.Title))
+	w.add(fmt.Sprintf("%s @@ %s", vector, query))
+	rank = fmt.Sprintf("ts_rank(%s, %s)::float8", vector, query)
+	headline = fmt.Sprintf("ts_headline('%s', movies.title, %s, 'StartSel=<b>, StopSel=</b>, HighlightAll=true')", lang, query)
+	return "movies", rank, headline
+}
+
+func (m MovieModel) queries() movieQueries {
+	return movieQueries{DB: m.DB, Timeout: m.Timeout, dialect: &postgresMovies}
+}
+
+// GetAll() lists the movies matching f, one page at a time.
+func (m MovieModel) GetAll(ctx context.Context, f MovieFilter, filter Filters) ([]*Movie, Metadata, error) {
+	return m.queries().getAll(ctx, f, filter)
+}
+
+// Each() streams every movie matching f to fn in id order, without loading
+// the whole result set into memory. Iteration stops at the first error
+// returned by fn.
+func (m MovieModel) Each(ctx context.Context, f MovieFilter, fn func(*Movie) error) error {
+	return m.queries().each(ctx, f, fn)
+}
+
+// Facets() counts the movies matching f by each of the named facets. All
+// facets are computed by a single query over the filtered set.
+func (m MovieModel) Facets(ctx context.Context, f MovieFilter, names []string) (map[string][]FacetCount, error) {
+	return m.queries().facets(ctx, f, names)
 }
@@ -0,0 +1,494 @@
+package data
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// movieMemModel is the in-memory MovieRepository. Title searches match
+// whole words without stemming whatever the language, and suggestions use
+// an approximation of pg_trgm's word similarity.
+type movieMemModel struct {
+	s *memoryStore
+}
+
+func cloneMovie(movie *Movie) *Movie {
+	clone := *movie
+	clone.Genres = slices.Clone(movie.Genres)
+	clone.DeletedAt = nil
+	return &clone
+}
+
+func (m movieMemModel) insert(movie *Movie) {
+	movie.ID = m.s.nextID("movies")
+	movie.CreatedAt = now()
+	movie.Version = 1
+	movie.AverageRating, movie.RatingCount = 0, 0
+	m.s.movies[movie.ID] = cloneMovie(movie)
+}
+
+func (m movieMemModel) Insert(ctx context.Context, movie *Movie) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	m.insert(movie)
+	return nil
+}
+
+func (m movieMemModel) InsertBatch(ctx context.Context, movies []*Movie) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	for _, movie := range movies {
+		m.insert(movie)
+	}
+	return nil
+}
+
+// live() returns the stored movie with the given id unless it is missing or
+// in the trash.
+func (s *memoryStore) live(id int64) (*Movie, bool) {
+	movie, ok := s.movies[id]
+	if !ok || movie.DeletedAt != nil {
+		return nil, false
+	}
+	return movie, true
+}
+
+func (m movieMemModel) Get(ctx context.Context, id int64) (*Movie, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	movie, ok := m.s.live(id)
+	if !ok {
+		return nil, ErrRecordNotFound
+	}
+	return cloneMovie(movie), nil
+}
+
+func (m movieMemModel) Update(ctx context.Context, movie *Movie, editedBy int64) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	stored, ok := m.s.live(movie.ID)
+	if !ok || stored.Version != movie.Version {
+		return ErrEditConflict
+	}
+
+	revisions := m.s.revisions[stored.ID]
+	if !slices.ContainsFunc(revisions, func(r *Revision) bool { return r.Version == stored.Version }) {
+		m.s.revisions[stored.ID] = append(revisions, &Revision{
+			MovieID:    stored.ID,
+			Version:    stored.Version,
+			Title:      stored.Title,
+			Year:       stored.Year,
+			Runtime:    stored.Runtime,
+			Genres:     slices.Clone(stored.Genres),
+			EditedBy:   &editedBy,
+			ReplacedAt: now(),
+		})
+	}
+
+	stored.Title = movie.Title
+	stored.Year = movie.Year
+	stored.Runtime = movie.Runtime
+	stored.Genres = slices.Clone(movie.Genres)
+	stored.Version++
+	movie.Version = stored.Version
+	return nil
+}
+
+func (m movieMemModel) Delete(ctx context.Context, id int64, version int32) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	movie, ok := m.s.live(id)
+	if !ok {
+		return ErrRecordNotFound
+	}
+	if version != 0 && movie.Version != version {
+		return ErrEditConflict
+	}
+	deletedAt := now()
+	movie.DeletedAt = &deletedAt
+	return nil
+}
+
+func (m movieMemModel) Restore(ctx context.Context, id int64) (*Movie, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	movie, ok := m.s.movies[id]
+	if !ok || movie.DeletedAt == nil {
+		return nil, ErrRecordNotFound
+	}
+	movie.DeletedAt = nil
+	return cloneMovie(movie), nil
+}
+
+func (m movieMemModel) GetDeleted(ctx context.Context, filter Filters) ([]*Movie, Metadata, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	movies := []*Movie{}
+	for _, movie := range m.s.movies {
+		if movie.DeletedAt != nil {
+			clone := cloneMovie(movie)
+			deletedAt := *movie.DeletedAt
+			clone.DeletedAt = &deletedAt
+			movies = append(movies, clone)
+		}
+	}
+	slices.SortFunc(movies, func(a, b *Movie) int {
+		if c := b.DeletedAt.Compare(*a.DeletedAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+	movies, metadata := paginate(movies, filter)
+	return movies, metadata, nil
+}
+
+func (m movieMemModel) Purge(ctx context.Context, retention time.Duration) (int64, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	cutoff := time.Now().Add(-retention)
+	var purged int64
+	for id, movie := range m.s.movies {
+		if movie.DeletedAt != nil && movie.DeletedAt.Before(cutoff) {
+			m.s.deleteMovie(id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// deleteMovie() removes a movie together with the rows that reference it,
+// like the ON DELETE CASCADE foreign keys do.
+func (s *memoryStore) deleteMovie(id int64) {
+	delete(s.movies, id)
+	delete(s.revisions, id)
+	for key := range s.reviews {
+		if key.movieID == id {
+			delete(s.reviews, key)
+		}
+	}
+	for creditID, credit := range s.credits {
+		if credit.MovieID == id {
+			delete(s.credits, creditID)
+		}
+	}
+	for _, items := range s.listItems {
+		delete(items, id)
+	}
+}
+
+func (m movieMemModel) Suggest(ctx context.Context, q string, limit int, threshold float64) ([]*Suggestion, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	type scored struct {
+		*Suggestion
+		prefix bool
+	}
+	var matches []scored
+	prefix := strings.ToLower(q)
+	for _, movie := range m.s.movies {
+		if movie.DeletedAt != nil {
+			continue
+		}
+		suggestion := scored{
+			Suggestion: &Suggestion{ID: movie.ID, Title: movie.Title, Year: movie.Year, Score: wordSimilarity(q, movie.Title)},
+			prefix:     strings.HasPrefix(strings.ToLower(movie.Title), prefix),
+		}
+		if suggestion.prefix || suggestion.Score >= threshold {
+			matches = append(matches, suggestion)
+		}
+	}
+	slices.SortFunc(matches, func(a, b scored) int {
+		switch {
+		case a.prefix != b.prefix && a.prefix:
+			return -1
+		case a.prefix != b.prefix:
+			return 1
+		}
+		if c := cmp.Compare(b.Score, a.Score); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Title, b.Title)
+	})
+
+	suggestions := []*Suggestion{}
+	for _, match := range matches[:min(limit, len(matches))] {
+		suggestions = append(suggestions, match.Suggestion)
+	}
+	return suggestions, nil
+}
+
+// trigrams() returns the trigram set of text the way pg_trgm builds it:
+// every lower-cased word is padded with two spaces in front and one behind.
+func trigrams(text string) map[string]bool {
+	set := make(map[string]bool)
+	for _, word := range searchWords(text) {
+		padded := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			set[string(padded[i:i+3])] = true
+		}
+	}
+	return set
+}
+
+// wordSimilarity() approximates pg_trgm's word_similarity() as the share
+// of the trigrams of q that also occur in text.
+func wordSimilarity(q, text string) float64 {
+	want := trigrams(q)
+	if len(want) == 0 {
+		return 0
+	}
+	have := trigrams(text)
+	shared := 0
+	for trigram := range want {
+		if have[trigram] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(want))
+}
+
+// searchWords() splits text into lower-cased words, like the simple text
+// search configuration does.
+func searchWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// highlight() wraps the words of title found in words in <b> tags, like
+// ts_headline() with HighlightAll.
+func highlight(title string, words map[string]bool) string {
+	var b strings.Builder
+	start := -1
+	flush := func(end int) {
+		word := title[start:end]
+		if words[strings.ToLower(word)] {
+			word = "<b>" + word + "</b>"
+		}
+		b.WriteString(word)
+		start = -1
+	}
+	for i, r := range title {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			flush(i)
+		}
+		b.WriteRune(r)
+	}
+	if start >= 0 {
+		flush(len(title))
+	}
+	return b.String()
+}
+
+// match() reports whether movie passes f, filling in its relevance and
+// highlight when f searches by title.
+func (f MovieFilter) match(s *memoryStore, movie *Movie) bool {
+	if f.Title != "" {
+		query := searchWords(f.Title)
+		titleWords := searchWords(movie.Title)
+		if len(query) == 0 {
+			return false
+		}
+		wanted := make(map[string]bool, len(query))
+		for _, word := range query {
+			if !slices.Contains(titleWords, word) {
+				return false
+			}
+			wanted[word] = true
+		}
+		matched := 0
+		for _, word := range titleWords {
+			if wanted[word] {
+				matched++
+			}
+		}
+		movie.Relevance = float64(matched) / float64(len(titleWords))
+		movie.Highlight = highlight(movie.Title, wanted)
+	}
+	for _, genre := range f.Genres {
+		if !slices.Contains(movie.Genres, genre) {
+			return false
+		}
+	}
+	if len(f.GenresAny) > 0 && !slices.ContainsFunc(movie.Genres, func(g string) bool { return slices.Contains(f.GenresAny, g) }) {
+		return false
+	}
+	if slices.ContainsFunc(movie.Genres, func(g string) bool { return slices.Contains(f.ExcludeGenres, g) }) {
+		return false
+	}
+	if f.PersonID != 0 {
+		credited := false
+		for _, credit := range s.credits {
+			if credit.MovieID == movie.ID && credit.PersonID == f.PersonID {
+				credited = true
+				break
+			}
+		}
+		if !credited {
+			return false
+		}
+	}
+	switch {
+	case f.YearMin != 0 && movie.Year < f.YearMin,
+		f.YearMax != 0 && movie.Year > f.YearMax,
+		f.RuntimeMin != 0 && int32(movie.Runtime) < f.RuntimeMin,
+		f.RuntimeMax != 0 && int32(movie.Runtime) > f.RuntimeMax,
+		!f.CreatedAfter.IsZero() && !movie.CreatedAt.After(f.CreatedAfter),
+		!f.CreatedBefore.IsZero() && !movie.CreatedAt.Before(f.CreatedBefore):
+		return false
+	}
+	return true
+}
+
+// matching() returns copies of the live movies passing f in id order.
+func (s *memoryStore) matching(f MovieFilter) []*Movie {
+	movies := []*Movie{}
+	for _, stored := range s.movies {
+		if stored.DeletedAt != nil {
+			continue
+		}
+		movie := cloneMovie(stored)
+		if f.match(s, movie) {
+			movies = append(movies, movie)
+		}
+	}
+	slices.SortFunc(movies, compareMovieIDs)
+	return movies
+}
+
+func compareMovieIDs(a, b *Movie) int {
+	return cmp.Compare(a.ID, b.ID)
+}
+
+// compareMovieColumn() compares movies by one of the sortable columns.
+func compareMovieColumn(column string) func(a, b *Movie) int {
+	switch column {
+	case "title":
+		return func(a, b *Movie) int { return cmp.Compare(a.Title, b.Title) }
+	case "year":
+		return func(a, b *Movie) int { return cmp.Compare(a.Year, b.Year) }
+	case "runtime":
+		return func(a, b *Movie) int { return cmp.Compare(a.Runtime, b.Runtime) }
+	case "average_rating":
+		return func(a, b *Movie) int { return cmp.Compare(a.AverageRating, b.AverageRating) }
+	case "rating_count":
+		return func(a, b *Movie) int { return cmp.Compare(a.RatingCount, b.RatingCount) }
+	case "relevance":
+		return func(a, b *Movie) int { return cmp.Compare(a.Relevance, b.Relevance) }
+	default:
+		return compareMovieIDs
+	}
+}
+
+func (m movieMemModel) GetAll(ctx context.Context, f MovieFilter, filter Filters) ([]*Movie, Metadata, error) {
+	m.s.mu.Lock()
+	movies := m.s.matching(f)
+	m.s.mu.Unlock()
+
+	order := orderBy(filter, compareMovieColumn(filter.sortColumn()), compareMovieIDs)
+	slices.SortStableFunc(movies, order)
+	if filter.Cursor != "" {
+		return m.getAllByCursor(movies, filter, order)
+	}
+
+	page, metadata := paginate(movies, filter)
+	return page, offsetMetadata(page, filter, (filter.Page-1)*filter.PageSize, metadata.TotalRecords), nil
+}
+
+// getAllByCursor() picks the rows next to the cursor position out of the
+// sorted movies, fetching them in the same order the SQL keyset query
+// would.
+func (m movieMemModel) getAllByCursor(movies []*Movie, filter Filters, order func(a, b *Movie) int) ([]*Movie, Metadata, error) {
+	c, pivot, err := decodeMovieCursor(filter)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	page := []*Movie{}
+	if c.Prev {
+		for i := len(movies) - 1; i >= 0 && len(page) <= filter.PageSize; i-- {
+			if order(movies[i], pivot) < 0 {
+				page = append(page, movies[i])
+			}
+		}
+	} else {
+		for i := 0; i < len(movies) && len(page) <= filter.PageSize; i++ {
+			if order(pivot, movies[i]) < 0 {
+				page = append(page, movies[i])
+			}
+		}
+	}
+	page, metadata := cursorPage(page, filter, c)
+	return page, metadata, nil
+}
+
+func (m movieMemModel) Each(ctx context.Context, f MovieFilter, fn func(*Movie) error) error {
+	// The matches are copied out first so fn, which usually writes to a
+	// client, doesn't run with the store locked.
+	m.s.mu.Lock()
+	movies := m.s.matching(f)
+	m.s.mu.Unlock()
+	for _, movie := range movies {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(movie); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runtimeBucket() returns the runtime_bucket facet value of a runtime.
+func runtimeBucket(runtime Runtime) string {
+	switch {
+	case runtime < 90:
+		return runtimeBuckets[0]
+	case runtime < 120:
+		return runtimeBuckets[1]
+	case runtime < 150:
+		return runtimeBuckets[2]
+	default:
+		return runtimeBuckets[3]
+	}
+}
+
+func (m movieMemModel) Facets(ctx context.Context, f MovieFilter, names []string) (map[string][]FacetCount, error) {
+	m.s.mu.Lock()
+	movies := m.s.matching(f)
+	m.s.mu.Unlock()
+
+	facets := make(map[string][]FacetCount, len(names))
+	for _, name := range names {
+		counts := make(map[string]int)
+		for _, movie := range movies {
+			switch name {
+			case FacetGenres:
+				for _, genre := range movie.Genres {
+					counts[genre]++
+				}
+			case FacetDecade:
+				counts[fmt.Sprintf("%ds", movie.Year/10*10)]++
+			case FacetRuntimeBucket:
+				counts[runtimeBucket(movie.Runtime)]++
+			}
+		}
+		facets[name] = []FacetCount{}
+		for value, count := range counts {
+			facets[name] = append(facets[name], FacetCount{Value: value, Count: count})
+		}
+		sortFacet(name, facets[name])
+	}
+	return facets, nil
+}
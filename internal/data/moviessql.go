@@ -0,0 +1,399 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// movieDialect holds the parts of the movie listing SQL that differ between
+// PostgreSQL and SQLite. The queries themselves are built and run by
+// movieQueries for both.
+type movieDialect struct {
+	// array wraps a string slice as a query argument or scan destination
+	// for the genres column.
+	array func(a *[]string) interface{}
+	// time converts t for comparing with a timestamp column.
+	time func(t time.Time) interface{}
+	// search adds the title search of f to w and returns the FROM clause
+	// and the rank and headline expressions for the select list.
+	search func(f MovieFilter, w *sqlWhere) (from, rank, headline string)
+	// genresAll, genresAny and genresNone match the genres column against
+	// the array argument placeholder.
+	genresAll, genresAny, genresNone func(placeholder string) string
+	// facetQueries maps each facet to a query grouping the matched CTE by
+	// the facet value.
+	facetQueries map[string]string
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// movieColumns is the column list scanned by scanMovie().
+const movieColumns = `movies.id, movies.created_at, movies.title, movies.year, movies.runtime,
+	movies.genres, movies.version, movies.average_rating, movies.rating_count`
+
+// sqlWhere collects the conditions of a WHERE clause together with their
+// positional arguments, so conditions can be composed without counting
+// placeholders by hand.
+type sqlWhere struct {
+	conditions []string
+	args       []interface{}
+}
+
+// arg() registers v as the next positional argument and returns its
+// placeholder.
+func (w *sqlWhere) arg(v interface{}) string {
+	w.args = append(w.args, v)
+	return "$" + strconv.Itoa(len(w.args))
+}
+
+func (w *sqlWhere) add(condition string) {
+	w.conditions = append(w.conditions, condition)
+}
+
+func (w *sqlWhere) String() string {
+	if len(w.conditions) == 0 {
+		return "TRUE"
+	}
+	return strings.Join(w.conditions, "\n\tAND ")
+}
+
+// where() adds the conditions of f to w and returns the FROM clause and the
+// rank and headline expressions for the select list.
+func (d *movieDialect) where(f MovieFilter, w *sqlWhere) (from, rank, headline string) {
+	w.add("movies.deleted_at IS NULL")
+	from, rank, headline = d.search(f, w)
+	if len(f.Genres) > 0 {
+		w.add(d.genresAll(w.arg(d.array(&f.Genres))))
+	}
+	if len(f.GenresAny) > 0 {
+		w.add(d.genresAny(w.arg(d.array(&f.GenresAny))))
+	}
+	if len(f.ExcludeGenres) > 0 {
+		w.add(d.genresNone(w.arg(d.array(&f.ExcludeGenres))))
+	}
+	if f.PersonID != 0 {
+		w.add("movies.id IN (SELECT movie_id FROM credits WHERE person_id = " + w.arg(f.PersonID) + ")")
+	}
+	if f.YearMin != 0 {
+		w.add("movies.year >= " + w.arg(f.YearMin))
+	}
+	if f.YearMax != 0 {
+		w.add("movies.year <= " + w.arg(f.YearMax))
+	}
+	if f.RuntimeMin != 0 {
+		w.add("movies.runtime >= " + w.arg(f.RuntimeMin))
+	}
+	if f.RuntimeMax != 0 {
+		w.add("movies.runtime <= " + w.arg(f.RuntimeMax))
+	}
+	if !f.CreatedAfter.IsZero() {
+		w.add("movies.created_at > " + w.arg(d.time(f.CreatedAfter)))
+	}
+	if !f.CreatedBefore.IsZero() {
+		w.add("movies.created_at < " + w.arg(d.time(f.CreatedBefore)))
+	}
+	return from, rank, headline
+}
+
+// selectMovies() builds the query shared by getAll(), getAllByCursor() and
+// each(). extra adds conditions that need the rank expression.
+func (d *movieDialect) selectMovies(f MovieFilter, w *sqlWhere, prefix string, extra func(rank string), orderBy func(rank string) string) string {
+	from, rank, headline := d.where(f, w)
+	if extra != nil {
+		extra(rank)
+	}
+	return fmt.Sprintf(`
+	SELECT %s%s, %s, %s
+	FROM %s
+	WHERE %s
+	ORDER BY %s`, prefix, movieColumns, rank, headline, from, w.String(), orderBy(rank))
+}
+
+func (d *movieDialect) scanMovie(rows *sql.Rows, movie *Movie, dest ...interface{}) error {
+	dest = append(dest,
+		&movie.ID,
+		&movie.CreatedAt,
+		&movie.Title,
+		&movie.Year,
+		&movie.Runtime,
+		d.array(&movie.Genres),
+		&movie.Version,
+		&movie.AverageRating,
+		&movie.RatingCount,
+		&movie.Relevance,
+		&movie.Highlight,
+	)
+	return rows.Scan(dest...)
+}
+
+// sortExpression() maps the sort column to SQL, replacing the virtual
+// relevance column with the rank expression.
+func sortExpression(filter Filters, rank string) string {
+	column := filter.sortColumn()
+	if column == "relevance" {
+		return rank
+	}
+	return "movies." + column
+}
+
+// movieQueries runs the movie listing queries against DB using the SQL of
+// dialect. MovieModel and movieSQLiteModel hand their listing methods to it.
+type movieQueries struct {
+	DB      DBTX
+	Timeout time.Duration
+	dialect *movieDialect
+}
+
+// getAll() lists the movies matching f, one page at a time.
+func (q movieQueries) getAll(ctx context.Context, f MovieFilter, filter Filters) ([]*Movie, Metadata, error) {
+	if filter.Cursor != "" {
+		return q.getAllByCursor(ctx, f, filter)
+	}
+	limit, offset := filter.PageSize, (filter.Page-1)*filter.PageSize
+	var w sqlWhere
+	query := q.dialect.selectMovies(f, &w, "count(*) OVER(), ", nil, func(rank string) string {
+		return fmt.Sprintf("%s %s, movies.id ASC LIMIT %s OFFSET %s",
+			sortExpression(filter, rank), filter.sortDirection(), w.arg(limit), w.arg(offset))
+	})
+
+	ctx, cancel := context.WithTimeout(ctx, q.Timeout)
+	defer cancel()
+	rows, err := q.DB.QueryContext(ctx, query, w.args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	movies := []*Movie{}
+	for rows.Next() {
+		var movie Movie
+		err := q.dialect.scanMovie(rows, &movie, &totalRecords)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		movies = append(movies, &movie)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+	return movies, offsetMetadata(movies, filter, offset, totalRecords), nil
+}
+
+// offsetMetadata() describes a page fetched by offset, adding cursors so
+// clients can switch to keyset pagination from any page.
+func offsetMetadata(movies []*Movie, filter Filters, offset, totalRecords int) Metadata {
+	metadata := calculateMetadata(totalRecords, filter.Page, filter.PageSize)
+	if len(movies) > 0 && offset+len(movies) < totalRecords {
+		metadata.NextCursor = movieCursor(movies[len(movies)-1], filter, false)
+	}
+	if len(movies) > 0 && offset > 0 {
+		metadata.PrevCursor = movieCursor(movies[0], filter, true)
+	}
+	return metadata
+}
+
+// getAllByCursor() is the keyset-paginated form of getAll(). Rows are
+// selected relative to the sort key and id stored in filter.Cursor, so deep
+// pages cost the same as the first one and concurrent inserts don't shift
+// the page boundaries.
+func (q movieQueries) getAllByCursor(ctx context.Context, f MovieFilter, filter Filters) ([]*Movie, Metadata, error) {
+	c, _, err := decodeMovieCursor(filter)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	keyCmp, idCmp := ">", ">"
+	direction, idDirection := filter.sortDirection(), "ASC"
+	if direction == "DESC" {
+		keyCmp = "<"
+	}
+	if c.Prev {
+		keyCmp, idCmp = flipComparison(keyCmp), "<"
+		direction, idDirection = flipDirection(direction), "DESC"
+	}
+
+	var w sqlWhere
+	keyset := func(rank string) {
+		column := sortExpression(filter, rank)
+		key, id := w.arg(c.Key), w.arg(c.ID)
+		// The key is text; columns convert it to their own type, but the
+		// rank expression needs it cast.
+		if filter.sortColumn() == "relevance" {
+			key = "CAST(" + key + " AS double precision)"
+		}
+		w.add(fmt.Sprintf("(%[1]s %[2]s %[3]s OR (%[1]s = %[3]s AND movies.id %[4]s %[5]s))",
+			column, keyCmp, key, idCmp, id))
+	}
+	// One extra row tells us whether another page exists past this one.
+	query := q.dialect.selectMovies(f, &w, "", keyset, func(rank string) string {
+		return fmt.Sprintf("%s %s, movies.id %s LIMIT %s",
+			sortExpression(filter, rank), direction, idDirection, w.arg(filter.PageSize+1))
+	})
+
+	ctx, cancel := context.WithTimeout(ctx, q.Timeout)
+	defer cancel()
+	rows, err := q.DB.QueryContext(ctx, query, w.args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	movies := []*Movie{}
+	for rows.Next() {
+		var movie Movie
+		err := q.dialect.scanMovie(rows, &movie)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		movies = append(movies, &movie)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	movies, metadata := cursorPage(movies, filter, c)
+	return movies, metadata, nil
+}
+
+// cursorPage() trims the rows fetched past cursor c, which hold one extra
+// row and come in reverse order when paging backwards, into the page and
+// its metadata.
+func cursorPage(movies []*Movie, filter Filters, c cursor) ([]*Movie, Metadata) {
+	hasMore := len(movies) > filter.PageSize
+	if hasMore {
+		movies = movies[:filter.PageSize]
+	}
+	if c.Prev {
+		for i, j := 0, len(movies)-1; i < j; i, j = i+1, j-1 {
+			movies[i], movies[j] = movies[j], movies[i]
+		}
+	}
+
+	metadata := Metadata{PageSize: filter.PageSize}
+	if len(movies) > 0 {
+		if hasMore || c.Prev {
+			metadata.NextCursor = movieCursor(movies[len(movies)-1], filter, false)
+		}
+		if hasMore || !c.Prev {
+			metadata.PrevCursor = movieCursor(movies[0], filter, true)
+		}
+	}
+	return movies, metadata
+}
+
+func movieCursor(movie *Movie, filter Filters, prev bool) string {
+	c := cursor{Sort: filter.Sort, ID: movie.ID, Prev: prev}
+	switch filter.sortColumn() {
+	case "id":
+		c.Key = strconv.FormatInt(movie.ID, 10)
+	case "title":
+		c.Key = movie.Title
+	case "year":
+		c.Key = strconv.FormatInt(int64(movie.Year), 10)
+	case "runtime":
+		c.Key = strconv.FormatInt(int64(movie.Runtime), 10)
+	case "average_rating":
+		c.Key = strconv.FormatFloat(movie.AverageRating, 'f', -1, 64)
+	case "rating_count":
+		c.Key = strconv.FormatInt(int64(movie.RatingCount), 10)
+	case "relevance":
+		c.Key = strconv.FormatFloat(movie.Relevance, 'f', -1, 64)
+	}
+	return c.encode()
+}
+
+func flipComparison(cmp string) string {
+	if cmp == ">" {
+		return "<"
+	}
+	return ">"
+}
+
+func flipDirection(direction string) string {
+	if direction == "ASC" {
+		return "DESC"
+	}
+	return "ASC"
+}
+
+// each() streams every movie matching f to fn in id order, without loading
+// the whole result set into memory. Iteration stops at the first error
+// returned by fn.
+func (q movieQueries) each(ctx context.Context, f MovieFilter, fn func(*Movie) error) error {
+	var w sqlWhere
+	query := q.dialect.selectMovies(f, &w, "", nil, func(string) string {
+		return "movies.id ASC"
+	})
+
+	ctx, cancel := context.WithTimeout(ctx, streamTimeoutFactor*q.Timeout)
+	defer cancel()
+	rows, err := q.DB.QueryContext(ctx, query, w.args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var movie Movie
+		err := q.dialect.scanMovie(rows, &movie)
+		if err != nil {
+			return err
+		}
+		if err := fn(&movie); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// facets() counts the movies matching f by each of the named facets. All
+// facets are computed by a single query over the filtered set.
+func (q movieQueries) facets(ctx context.Context, f MovieFilter, names []string) (map[string][]FacetCount, error) {
+	facets := make(map[string][]FacetCount, len(names))
+	if len(names) == 0 {
+		return facets, nil
+	}
+
+	var w sqlWhere
+	from, _, _ := q.dialect.where(f, &w)
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		facets[name] = []FacetCount{}
+		parts = append(parts, q.dialect.facetQueries[name])
+	}
+	query := fmt.Sprintf(`
+	WITH matched AS (
+		SELECT movies.year, movies.runtime, movies.genres
+		FROM %s
+		WHERE %s
+	)
+	%s`, from, w.String(), strings.Join(parts, "\n\tUNION ALL\n\t"))
+
+	ctx, cancel := context.WithTimeout(ctx, q.Timeout)
+	defer cancel()
+	rows, err := q.DB.QueryContext(ctx, query, w.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		var count FacetCount
+		if err := rows.Scan(&name, &count.Value, &count.Count); err != nil {
+			return nil, err
+		}
+		facets[name] = append(facets[name], count)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for name, counts := range facets {
+		sortFacet(name, counts)
+	}
+	return facets, nil
+}
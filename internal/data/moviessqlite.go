@@ -0,0 +1,391 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// movieSQLiteModel is the SQLite MovieRepository. Title searches use the
+// FTS5 tables of the migrations, which only stem English; other languages
+// match whole words like "simple". Suggestions rank titles with the
+// word_similarity() function registered in sqlite.go.
+type movieSQLiteModel struct {
+	DB      DBTX
+	Timeout time.Duration
+}
+
+func (m movieSQLiteModel) Insert(ctx context.Context, movie *Movie) error {
+	query := `
+	INSERT INTO movies (title, year, runtime, genres)
+	VALUES ($1, $2, $3, $4)
+	RETURNING id, created_at, version`
+
+	ctx, cancel := context.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+	return m.DB.QueryRowContext(ctx, query, movie.Title, movie.Year, movie.Runtime, jsonArray{&movie.Genres}).
+		Scan(&movie.ID, &movie.CreatedAt, &movie.Version)
+}
+
+// InsertBatch() inserts all movies inside one transaction, so either every
+// movie is created or none are.
+func (m movieSQLiteModel) InsertBatch(ctx context.Context, movies []*Movie) error {
+	query := `
+	INSERT INTO movies (title, year, runtime, genres)
+	VALUES ($1, $2, $3, $4)
+	RETURNING id, created_at, version`
+
+	ctx, cancel := context.WithTimeout(ctx, batchTimeoutFactor*m.Timeout)
+	defer cancel()
+
+	tx, err := beginTx(ctx, m.DB, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, movie := range movies {
+		err = stmt.QueryRowContext(ctx, movie.Title, movie.Year, movie.Runtime, jsonArray{&movie.Genres}).
+			Scan(&movie.ID, &movie.CreatedAt, &movie.Version)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (m movieSQLiteModel) Get(ctx context.Context, id int64) (*Movie, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+	query := `
+	SELECT id, created_at, title, year, runtime, genres, version, average_rating, rating_count
+	FROM movies
+	WHERE id = $1 AND deleted_at IS NULL`
+
+	var movie Movie
+	ctx, cancel := context.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&movie.ID,
+		&movie.CreatedAt,
+		&movie.Title,
+		&movie.Year,
+		&movie.Runtime,
+		jsonArray{&movie.Genres},
+		&movie.Version,
+		&movie.AverageRating,
+		&movie.RatingCount,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &movie, nil
+}
+
+// Update() saves movie and archives the row it replaces in movie_revisions,
+// recording editedBy as the user who made the change. SQLite has no
+// data-modifying CTEs, so both statements share a transaction instead.
+func (m movieSQLiteModel) Update(ctx context.Context, movie *Movie, editedBy int64) error {
+	archive := `
+	INSERT INTO movie_revisions (movie_id, version, title, year, runtime, genres, edited_by)
+	SELECT id, version, title, year, runtime, genres, $3
+	FROM movies
+	WHERE id = $1 AND version = $2 AND deleted_at IS NULL
+	ON CONFLICT DO NOTHING`
+	query := `
+	UPDATE movies
+	SET title = $1, year = $2, runtime = $3, genres = $4, version = version + 1
+	WHERE id = $5 AND version = $6 AND deleted_at IS NULL
+	RETURNING version`
+	args := []interface{}{
+		movie.Title,
+		movie.Year,
+		movie.Runtime,
+		jsonArray{&movie.Genres},
+		movie.ID,
+		movie.Version,
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+	tx, err := beginTx(ctx, m.DB, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, archive, movie.ID, movie.Version, editedBy)
+	if err != nil {
+		return err
+	}
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&movie.Version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrEditConflict
+		}
+		return err
+	}
+	return tx.Commit()
+}
+
+// Delete() moves a movie to the trash. It stays restorable until Purge()
+// removes it for good. A non-zero version makes the delete conditional on
+// the movie still being at that version.
+func (m movieSQLiteModel) Delete(ctx context.Context, id int64, version int32) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+	query := `
+	UPDATE movies
+	SET deleted_at = $3
+	WHERE id = $1 AND ($2 = 0 OR version = $2) AND deleted_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+	result, err := m.DB.ExecContext(ctx, query, id, version, sqliteTime(time.Now()))
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		var exists bool
+		err := m.DB.QueryRowContext(ctx,
+			`SELECT EXISTS(SELECT 1 FROM movies WHERE id = $1 AND deleted_at IS NULL)`, id).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return ErrEditConflict
+		}
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// Restore() takes a movie back out of the trash.
+func (m movieSQLiteModel) Restore(ctx context.Context, id int64) (*Movie, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+	query := `
+	UPDATE movies
+	SET deleted_at = NULL
+	WHERE id = $1 AND deleted_at IS NOT NULL`
+
+	ctx, cancel := context.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if rowsAffected == 0 {
+		return nil, ErrRecordNotFound
+	}
+	return m.Get(ctx, id)
+}
+
+// GetDeleted() lists the movies in the trash, most recently deleted first.
+func (m movieSQLiteModel) GetDeleted(ctx context.Context, filter Filters) ([]*Movie, Metadata, error) {
+	query := `
+	SELECT count(*) OVER(), id, created_at, title, year, runtime, genres, version, average_rating,
+	rating_count, deleted_at
+	FROM movies
+	WHERE deleted_at IS NOT NULL
+	ORDER BY deleted_at DESC, id ASC LIMIT $1 OFFSET $2`
+
+	ctx, cancel := context.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+	rows, err := m.DB.QueryContext(ctx, query, filter.PageSize, (filter.Page-1)*filter.PageSize)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	movies := []*Movie{}
+	for rows.Next() {
+		var movie Movie
+		err := rows.Scan(
+			&totalRecords,
+			&movie.ID,
+			&movie.CreatedAt,
+			&movie.Title,
+			&movie.Year,
+			&movie.Runtime,
+			jsonArray{&movie.Genres},
+			&movie.Version,
+			&movie.AverageRating,
+			&movie.RatingCount,
+			&movie.DeletedAt,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		movies = append(movies, &movie)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+	return movies, calculateMetadata(totalRecords, filter.Page, filter.PageSize), nil
+}
+
+// Purge() permanently deletes movies that have been in the trash for
+// longer than retention, returning how many were removed.
+func (m movieSQLiteModel) Purge(ctx context.Context, retention time.Duration) (int64, error) {
+	query := `
+	DELETE FROM movies
+	WHERE deleted_at < $1`
+
+	ctx, cancel := context.WithTimeout(ctx, purgeTimeoutFactor*m.Timeout)
+	defer cancel()
+	result, err := m.DB.ExecContext(ctx, query, sqliteTime(time.Now().Add(-retention)))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// Suggest() returns up to limit titles close to q, for search-as-you-type.
+// Titles starting with q rank first; the rest are matched by word
+// similarity, so small typos still find the movie. LIKE ignores the case of
+// ASCII letters, as ILIKE does.
+func (m movieSQLiteModel) Suggest(ctx context.Context, q string, limit int, threshold float64) ([]*Suggestion, error) {
+	query := `
+	SELECT id, title, year, word_similarity($1, title)
+	FROM movies
+	WHERE (word_similarity($1, title) >= $4 OR title LIKE $2 ESCAPE '\') AND deleted_at IS NULL
+	ORDER BY title LIKE $2 ESCAPE '\' DESC, word_similarity($1, title) DESC, title ASC
+	LIMIT $3`
+	prefix := likeEscaper.Replace(q) + "%"
+
+	ctx, cancel := context.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+	rows, err := m.DB.QueryContext(ctx, query, q, prefix, limit, threshold)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	suggestions := []*Suggestion{}
+	for rows.Next() {
+		var suggestion Suggestion
+		err := rows.Scan(&suggestion.ID, &suggestion.Title, &suggestion.Year, &suggestion.Score)
+		if err != nil {
+			return nil, err
+		}
+		suggestions = append(suggestions, &suggestion)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return suggestions, nil
+}
+
+// sqliteMovies is the SQLite movieDialect. Genres are matched against the
+// JSON array column with json_each().
+var sqliteMovies = movieDialect{
+	array:  func(a *[]string) interface{} { return jsonArray{a} },
+	time:   func(t time.Time) interface{} { return sqliteTime(t) },
+	search: sqliteSearch,
+	genresAll: func(p string) string {
+		return `NOT EXISTS (SELECT 1 FROM json_each(` + p + `) AS wanted
+		WHERE wanted.value NOT IN (SELECT value FROM json_each(movies.genres)))`
+	},
+	genresAny: func(p string) string {
+		return `EXISTS (SELECT 1 FROM json_each(movies.genres) AS genre
+		WHERE genre.value IN (SELECT value FROM json_each(` + p + `)))`
+	},
+	genresNone: func(p string) string {
+		return `NOT EXISTS (SELECT 1 FROM json_each(movies.genres) AS genre
+		WHERE genre.value IN (SELECT value FROM json_each(` + p + `)))`
+	},
+	facetQueries: map[string]string{
+		FacetGenres:        `SELECT 'genres', genre.value, count(*) FROM matched, json_each(matched.genres) AS genre GROUP BY genre.value`,
+		FacetDecade:        `SELECT 'decade', (year / 10 * 10) || 's', count(*) FROM matched GROUP BY 2`,
+		FacetRuntimeBucket: postgresMovies.facetQueries[FacetRuntimeBucket],
+	},
+}
+
+// sqliteSearchTable() returns the FTS5 table indexing titles for the
+// search language of f.
+func (f MovieFilter) sqliteSearchTable() string {
+	if f.language() == "english" {
+		return "movies_fts_english"
+	}
+	return "movies_fts"
+}
+
+// ftsQuery() turns text into an FTS5 query matching every word of it, like
+// plainto_tsquery() does. The words are quoted so that none of them is read
+// as an FTS5 operator.
+func ftsQuery(text string) string {
+	words := searchWords(text)
+	for i, word := range words {
+		words[i] = `"` + word + `"`
+	}
+	return strings.Join(words, " ")
+}
+
+// sqliteSearch() joins the FTS5 matches for the title search of f and
+// returns the rank and highlighted title.
+func sqliteSearch(f MovieFilter, w *sqlWhere) (from, rank, headline string) {
+	if f.Title == "" {
+		return "movies", "0.0", "''"
+	}
+	query := ftsQuery(f.Title)
+	if query == "" {
+		w.add("FALSE")
+		return "movies", "0.0", "''"
+	}
+	// FTS5 only computes bm25() and highlight() while it runs the full-text
+	// query itself, so the matches are ranked in a subquery first. bm25()
+	// scores better matches lower.
+	from = fmt.Sprintf(`movies INNER JOIN (
+		SELECT rowid, -bm25(%[1]s) AS rank, highlight(%[1]s, 0, '<b>', '</b>') AS headline
+		FROM %[1]s
+		WHERE %[1]s MATCH %[2]s
+	) AS search ON search.rowid = movies.id`, f.sqliteSearchTable(), w.arg(query))
+	return from, "search.rank", "search.headline"
+}
+
+func (m movieSQLiteModel) queries() movieQueries {
+	return movieQueries{DB: m.DB, Timeout: m.Timeout, dialect: &sqliteMovies}
+}
+
+// GetAll() lists the movies matching f, one page at a time.
+func (m movieSQLiteModel) GetAll(ctx context.Context, f MovieFilter, filter Filters) ([]*Movie, Metadata, error) {
+	return m.queries().getAll(ctx, f, filter)
+}
+
+// Each() streams every movie matching f to fn in id order. Iteration stops
+// at the first error returned by fn.
+func (m movieSQLiteModel) Each(ctx context.Context, f MovieFilter, fn func(*Movie) error) error {
+	return m.queries().each(ctx, f, fn)
+}
+
+// Facets() counts the movies matching f by each of the named facets.
+func (m movieSQLiteModel) Facets(ctx context.Context, f MovieFilter, names []string) (map[string][]FacetCount, error) {
+	return m.queries().facets(ctx, f, names)
+}
@@ -0,0 +1,37 @@
+package data
+
+import (
+	"context"
+	"time"
+
+	"github.com/arian-nj/site/back/internal/validator"
+)
+
+type Person struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"-"`
+	Name      string    `json:"name"`
+	BirthYear *int32    `json:"birth_year"`
+	Bio       string    `json:"bio"`
+	Version   int32     `json:"version"`
+}
+
+type PersonRepository interface {
+	Insert(ctx context.Context, person *Person) error
+	Get(ctx context.Context, id int64) (*Person, error)
+	Update(ctx context.Context, person *Person) error
+	Delete(ctx context.Context, id int64) error
+	GetAll(ctx context.Context, name string, filter Filters) ([]*Person, Metadata, error)
+}
+
+func ValidatePerson(v *validator.Validator, person *Person) {
+	v.Check(person.Name != "", "name", "must be provided")
+	v.Check(len(person.Name) <= 500, "name", "must not be more than 500 bytes long")
+
+	if person.BirthYear != nil {
+		v.Check(*person.BirthYear >= 1800, "birth_year", "must be greater than 1800")
+		v.Check(*person.BirthYear <= int32(time.Now().Year()), "birth_year", "must not be in the future")
+	}
+
+	v.Check(len(person.Bio) <= 10_000, "bio", "must not be more than 10000 bytes long")
+}
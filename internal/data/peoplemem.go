@@ -0,0 +1,91 @@
+package data
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+)
+
+type personMemModel struct {
+	s *memoryStore
+}
+
+func clonePerson(person *Person) *Person {
+	clone := *person
+	if person.BirthYear != nil {
+		year := *person.BirthYear
+		clone.BirthYear = &year
+	}
+	return &clone
+}
+
+func (m personMemModel) Insert(ctx context.Context, person *Person) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	person.ID = m.s.nextID("people")
+	person.CreatedAt = now()
+	person.Version = 1
+	m.s.people[person.ID] = clonePerson(person)
+	return nil
+}
+
+func (m personMemModel) Get(ctx context.Context, id int64) (*Person, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	person, ok := m.s.people[id]
+	if !ok {
+		return nil, ErrRecordNotFound
+	}
+	return clonePerson(person), nil
+}
+
+func (m personMemModel) Update(ctx context.Context, person *Person) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	stored, ok := m.s.people[person.ID]
+	if !ok || stored.Version != person.Version {
+		return ErrEditConflict
+	}
+	person.Version++
+	m.s.people[person.ID] = clonePerson(person)
+	return nil
+}
+
+func (m personMemModel) Delete(ctx context.Context, id int64) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	if _, ok := m.s.people[id]; !ok {
+		return ErrRecordNotFound
+	}
+	delete(m.s.people, id)
+	for creditID, credit := range m.s.credits {
+		if credit.PersonID == id {
+			delete(m.s.credits, creditID)
+		}
+	}
+	return nil
+}
+
+func (m personMemModel) GetAll(ctx context.Context, name string, filter Filters) ([]*Person, Metadata, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	people := []*Person{}
+	for _, person := range m.s.people {
+		if strings.Contains(strings.ToLower(person.Name), strings.ToLower(name)) {
+			people = append(people, clonePerson(person))
+		}
+	}
+
+	byID := func(a, b *Person) int { return cmp.Compare(a.ID, b.ID) }
+	column := byID
+	switch filter.sortColumn() {
+	case "name":
+		column = func(a, b *Person) int { return cmp.Compare(a.Name, b.Name) }
+	case "birth_year":
+		column = func(a, b *Person) int { return compareNullable(a.BirthYear, b.BirthYear) }
+	}
+	slices.SortFunc(people, orderBy(filter, column, byID))
+	people, metadata := paginate(people, filter)
+	return people, metadata, nil
+}
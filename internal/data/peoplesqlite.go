@@ -0,0 +1,141 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// personSQLiteModel is the SQLite PersonRepository.
+type personSQLiteModel struct {
+	DB      DBTX
+	Timeout time.Duration
+}
+
+func (m personSQLiteModel) Insert(ctx context.Context, person *Person) error {
+	query := `
+	INSERT INTO people (name, birth_year, bio)
+	VALUES ($1, $2, $3)
+	RETURNING id, created_at, version`
+
+	ctx, cancel := context.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+	return m.DB.QueryRowContext(ctx, query, person.Name, person.BirthYear, person.Bio).
+		Scan(&person.ID, &person.CreatedAt, &person.Version)
+}
+
+func (m personSQLiteModel) Get(ctx context.Context, id int64) (*Person, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+	query := `
+	SELECT id, created_at, name, birth_year, bio, version
+	FROM people
+	WHERE id = $1`
+
+	var person Person
+	ctx, cancel := context.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&person.ID,
+		&person.CreatedAt,
+		&person.Name,
+		&person.BirthYear,
+		&person.Bio,
+		&person.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &person, nil
+}
+
+func (m personSQLiteModel) Update(ctx context.Context, person *Person) error {
+	query := `
+	UPDATE people
+	SET name = $1, birth_year = $2, bio = $3, version = version + 1
+	WHERE id = $4 AND version = $5
+	RETURNING version`
+
+	ctx, cancel := context.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+	err := m.DB.QueryRowContext(ctx, query, person.Name, person.BirthYear, person.Bio, person.ID, person.Version).
+		Scan(&person.Version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrEditConflict
+		}
+		return err
+	}
+	return nil
+}
+
+func (m personSQLiteModel) Delete(ctx context.Context, id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+	query := `
+	DELETE FROM people
+	WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+func (m personSQLiteModel) GetAll(ctx context.Context, name string, filter Filters) ([]*Person, Metadata, error) {
+	query := fmt.Sprintf(`
+	SELECT count(*) OVER(), id, created_at, name, birth_year, bio, version
+	FROM people
+	WHERE (name LIKE '%%' || $1 || '%%' ESCAPE '\' OR $1 = '')
+	ORDER BY %s %s %s, id ASC LIMIT $2 OFFSET $3`, filter.sortColumn(), filter.sortDirection(), sqliteNulls(filter.sortDirection()))
+
+	ctx, cancel := context.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+	rows, err := m.DB.QueryContext(ctx, query, likeEscaper.Replace(name), filter.PageSize, (filter.Page-1)*filter.PageSize)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	people := []*Person{}
+	for rows.Next() {
+		var person Person
+		err := rows.Scan(
+			&totalRecords,
+			&person.ID,
+			&person.CreatedAt,
+			&person.Name,
+			&person.BirthYear,
+			&person.Bio,
+			&person.Version,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		people = append(people, &person)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+	return people, calculateMetadata(totalRecords, filter.Page, filter.PageSize), nil
+}
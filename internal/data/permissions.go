@@ -0,0 +1,26 @@
+package data
+
+import "context"
+
+const (
+	PermissionMoviesRead  = "movies:read"
+	PermissionMoviesWrite = "movies:write"
+)
+
+// Permissions holds the permission codes (like "movies:read") granted to a
+// single user.
+type Permissions []string
+
+func (p Permissions) Include(code string) bool {
+	for i := range p {
+		if code == p[i] {
+			return true
+		}
+	}
+	return false
+}
+
+type PermissionRepository interface {
+	GetAllForUser(ctx context.Context, userID int64) (Permissions, error)
+	AddForUser(ctx context.Context, userID int64, codes ...string) error
+}
@@ -0,0 +1,34 @@
+package data
+
+import (
+	"context"
+	"slices"
+)
+
+// knownPermissions are the codes seeded into the permissions table by the
+// migrations; granting any other code has no effect.
+var knownPermissions = []string{PermissionMoviesRead, PermissionMoviesWrite}
+
+type permissionMemModel struct {
+	s *memoryStore
+}
+
+func (m permissionMemModel) GetAllForUser(ctx context.Context, userID int64) (Permissions, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	return slices.Clone(m.s.permissions[userID]), nil
+}
+
+func (m permissionMemModel) AddForUser(ctx context.Context, userID int64, codes ...string) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	if _, ok := m.s.users[userID]; !ok {
+		return ErrRecordNotFound
+	}
+	for _, code := range codes {
+		if slices.Contains(knownPermissions, code) && !m.s.permissions[userID].Include(code) {
+			m.s.permissions[userID] = append(m.s.permissions[userID], code)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package data
+
+import (
+	"context"
+	"time"
+)
+
+// permissionSQLiteModel is the SQLite PermissionRepository.
+type permissionSQLiteModel struct {
+	DB      DBTX
+	Timeout time.Duration
+}
+
+// GetAllForUser() returns all permission codes for a specific user.
+func (m permissionSQLiteModel) GetAllForUser(ctx context.Context, userID int64) (Permissions, error) {
+	query := `
+SELECT permissions.code
+FROM permissions
+INNER JOIN users_permissions ON users_permissions.permission_id = permissions.id
+INNER JOIN users ON users_permissions.user_id = users.id
+WHERE users.id = $1`
+	ctx, cancel := context.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var permissions Permissions
+	for rows.Next() {
+		var permission string
+		err := rows.Scan(&permission)
+		if err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, permission)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return permissions, nil
+}
+
+// AddForUser() grants the given permission codes to a specific user.
+func (m permissionSQLiteModel) AddForUser(ctx context.Context, userID int64, codes ...string) error {
+	query := `
+INSERT INTO users_permissions
+SELECT $1, permissions.id FROM permissions WHERE permissions.code IN (SELECT value FROM json_each($2))
+ON CONFLICT DO NOTHING`
+	ctx, cancel := context.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+	_, err := m.DB.ExecContext(ctx, query, userID, jsonArray{&codes})
+	return err
+}
@@ -0,0 +1,377 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/arian-nj/site/back/internal/migrate"
+	"github.com/arian-nj/site/back/migrations"
+)
+
+// backends lists the storage backends the repository tests run against.
+// Each open() returns empty Models. PostgreSQL is only tested when
+// TEST_DB_DSN points at a database the tests may wipe.
+var backends = []struct {
+	name string
+	open func(t *testing.T) *Models
+}{
+	{"memory", func(t *testing.T) *Models { return NewMemoryModels() }},
+	{"sqlite", openSQLite},
+	{"postgres", openPostgres},
+}
+
+func openSQLite(t *testing.T) *Models {
+	db, err := OpenDB(DBConfig{Driver: DriverSQLite, DSN: filepath.Join(t.TempDir(), "movies.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	fsys, err := migrations.ForDriver(DriverSQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := migrate.New(db, fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Dialect = migrate.SQLite
+	if err := m.Up(); err != nil {
+		t.Fatal(err)
+	}
+	return NewSQLiteModels(db, 5*time.Second)
+}
+
+func openPostgres(t *testing.T) *Models {
+	dsn := os.Getenv("TEST_DB_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DB_DSN not set")
+	}
+	db, err := OpenDB(DBConfig{Driver: DriverPostgres, DSN: dsn})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	m, err := migrate.New(db, migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Up(); err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`TRUNCATE users, movies, people RESTART IDENTITY CASCADE`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewModels(db, 5*time.Second)
+}
+
+// eachBackend() runs fn as a subtest against every backend.
+func eachBackend(t *testing.T, fn func(t *testing.T, models *Models)) {
+	for _, b := range backends {
+		t.Run(b.name, func(t *testing.T) {
+			fn(t, b.open(t))
+		})
+	}
+}
+
+func insertTestMovie(t *testing.T, models *Models) *Movie {
+	movie := &Movie{Title: "Moana", Year: 2016, Runtime: 107, Genres: []string{"animation", "adventure"}}
+	if err := models.Movie.Insert(context.Background(), movie); err != nil {
+		t.Fatal(err)
+	}
+	return movie
+}
+
+func insertTestUser(t *testing.T, models *Models, email string) *User {
+	user := &User{Name: "Alice", Email: email, Activated: true}
+	if err := user.Password.Set("pa55word1234"); err != nil {
+		t.Fatal(err)
+	}
+	if err := models.Users.Insert(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func TestMovieOptimisticLocking(t *testing.T) {
+	eachBackend(t, func(t *testing.T, models *Models) {
+		ctx := context.Background()
+		editor := insertTestUser(t, models, "alice@example.com")
+		movie := insertTestMovie(t, models)
+
+		stale, err := models.Movie.Get(ctx, movie.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		movie.Title = "Moana (2016)"
+		if err := models.Movie.Update(ctx, movie, editor.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		tests := []struct {
+			name string
+			run  func() error
+			want error
+		}{
+			{"update with a stale version", func() error {
+				stale.Title = "Vaiana"
+				return models.Movie.Update(ctx, stale, editor.ID)
+			}, ErrEditConflict},
+			{"delete with a stale version", func() error {
+				return models.Movie.Delete(ctx, movie.ID, stale.Version)
+			}, ErrEditConflict},
+			{"delete with the current version", func() error {
+				return models.Movie.Delete(ctx, movie.ID, movie.Version)
+			}, nil},
+			{"delete once in the trash", func() error {
+				return models.Movie.Delete(ctx, movie.ID, movie.Version)
+			}, ErrRecordNotFound},
+		}
+		for _, tt := range tests {
+			if err := tt.run(); !errors.Is(err, tt.want) {
+				t.Errorf("%s: got error %v, want %v", tt.name, err, tt.want)
+			}
+		}
+	})
+}
+
+func TestUserOptimisticLocking(t *testing.T) {
+	eachBackend(t, func(t *testing.T, models *Models) {
+		ctx := context.Background()
+		user := insertTestUser(t, models, "alice@example.com")
+		stale := *user
+
+		user.Name = "Alice Liddell"
+		if err := models.Users.Update(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+		stale.Name = "Alice L."
+		if err := models.Users.Update(ctx, &stale); !errors.Is(err, ErrEditConflict) {
+			t.Errorf("got error %v, want %v", err, ErrEditConflict)
+		}
+	})
+}
+
+func TestDuplicateEmail(t *testing.T) {
+	tests := []struct {
+		name  string
+		email string
+		want  error
+	}{
+		{"same email", "alice@example.com", ErrDuplicateEmail},
+		{"different case", "Alice@Example.com", ErrDuplicateEmail},
+		{"different email", "bob@example.com", nil},
+	}
+	eachBackend(t, func(t *testing.T, models *Models) {
+		ctx := context.Background()
+		insertTestUser(t, models, "alice@example.com")
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				user := &User{Name: "Someone", Email: tt.email}
+				if err := user.Password.Set("pa55word1234"); err != nil {
+					t.Fatal(err)
+				}
+				err := models.Users.Insert(ctx, user)
+				if !errors.Is(err, tt.want) {
+					t.Fatalf("insert: got error %v, want %v", err, tt.want)
+				}
+				if err != nil {
+					return
+				}
+				// Changing the email of an existing user is held to the
+				// same rule.
+				user.Email = "ALICE@example.com"
+				if err := models.Users.Update(ctx, user); !errors.Is(err, ErrDuplicateEmail) {
+					t.Errorf("update: got error %v, want %v", err, ErrDuplicateEmail)
+				}
+			})
+		}
+	})
+}
+
+func TestTokenExpiry(t *testing.T) {
+	tests := []struct {
+		name     string
+		ttl      time.Duration
+		scope    string
+		lookupAs string
+		want     error
+	}{
+		{"valid", time.Hour, ScopeAuthentication, ScopeAuthentication, nil},
+		{"expired", -time.Minute, ScopeAuthentication, ScopeAuthentication, ErrRecordNotFound},
+		{"other scope", time.Hour, ScopeActivation, ScopeAuthentication, ErrRecordNotFound},
+	}
+	eachBackend(t, func(t *testing.T, models *Models) {
+		ctx := context.Background()
+		user := insertTestUser(t, models, "alice@example.com")
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				token, err := models.Token.New(ctx, user.ID, tt.ttl, tt.scope)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got, err := models.Users.GetForToken(ctx, tt.lookupAs, token.Plaintext)
+				if !errors.Is(err, tt.want) {
+					t.Fatalf("got error %v, want %v", err, tt.want)
+				}
+				if err == nil && got.ID != user.ID {
+					t.Errorf("got user %d, want %d", got.ID, user.ID)
+				}
+			})
+		}
+	})
+}
+
+func TestMovieListing(t *testing.T) {
+	movies := []*Movie{
+		{Title: "Moana", Year: 2016, Runtime: 107, Genres: []string{"animation", "adventure"}},
+		{Title: "Black Panther", Year: 2018, Runtime: 134, Genres: []string{"action", "adventure"}},
+		{Title: "Deadpool", Year: 2016, Runtime: 108, Genres: []string{"action", "comedy"}},
+		{Title: "The Breakfast Club", Year: 1985, Runtime: 96, Genres: []string{"drama"}},
+	}
+	sortSafelist := []string{"id", "title", "year", "relevance", "-id", "-title", "-year", "-relevance"}
+	tests := []struct {
+		name   string
+		filter MovieFilter
+		sort   string
+		want   []string
+	}{
+		{"all genres", MovieFilter{Genres: []string{"action", "adventure"}}, "id",
+			[]string{"Black Panther"}},
+		{"any genre", MovieFilter{GenresAny: []string{"comedy", "drama"}}, "id",
+			[]string{"Deadpool", "The Breakfast Club"}},
+		{"excluded genre", MovieFilter{ExcludeGenres: []string{"action"}}, "title",
+			[]string{"Moana", "The Breakfast Club"}},
+		{"year range", MovieFilter{YearMin: 2000, YearMax: 2016}, "-title",
+			[]string{"Moana", "Deadpool"}},
+		{"title search", MovieFilter{Title: "panther"}, "-relevance",
+			[]string{"Black Panther"}},
+	}
+	eachBackend(t, func(t *testing.T, models *Models) {
+		ctx := context.Background()
+		for _, movie := range movies {
+			movie := *movie
+			if err := models.Movie.Insert(ctx, &movie); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				filter := Filters{Page: 1, PageSize: 20, Sort: tt.sort, SortSafelist: sortSafelist}
+				got, _, err := models.Movie.GetAll(ctx, tt.filter, filter)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if titles := movieTitles(got); !slices.Equal(titles, tt.want) {
+					t.Errorf("got %q, want %q", titles, tt.want)
+				}
+			})
+		}
+
+		t.Run("cursor", func(t *testing.T) {
+			filter := Filters{Page: 1, PageSize: 3, Sort: "-year", SortSafelist: sortSafelist}
+			first, metadata, err := models.Movie.GetAll(ctx, MovieFilter{}, filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			filter.Cursor = metadata.NextCursor
+			second, _, err := models.Movie.GetAll(ctx, MovieFilter{}, filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := []string{"Black Panther", "Moana", "Deadpool", "The Breakfast Club"}
+			if titles := movieTitles(append(first, second...)); !slices.Equal(titles, want) {
+				t.Errorf("got %q, want %q", titles, want)
+			}
+		})
+
+		t.Run("invalid cursor", func(t *testing.T) {
+			cursors := []cursor{
+				{Sort: "-year", Key: "not a year", ID: 1},
+				{Sort: "-year", Key: "99999999999", ID: 1},
+				{Sort: "title", Key: "Moana", ID: 1},
+			}
+			for _, c := range cursors {
+				filter := Filters{Page: 1, PageSize: 3, Sort: "-year", SortSafelist: sortSafelist, Cursor: c.encode()}
+				_, _, err := models.Movie.GetAll(ctx, MovieFilter{}, filter)
+				if !errors.Is(err, ErrInvalidCursor) {
+					t.Errorf("cursor %+v: got error %v, want %v", c, err, ErrInvalidCursor)
+				}
+			}
+		})
+
+		t.Run("facets", func(t *testing.T) {
+			facets, err := models.Movie.Facets(ctx, MovieFilter{YearMin: 2000}, []string{FacetGenres, FacetDecade})
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := []FacetCount{{"2010s", 3}}
+			if !slices.Equal(facets[FacetDecade], want) {
+				t.Errorf("decade: got %v, want %v", facets[FacetDecade], want)
+			}
+			genres := map[string]int{}
+			for _, count := range facets[FacetGenres] {
+				genres[count.Value] = count.Count
+			}
+			wantGenres := map[string]int{"action": 2, "adventure": 2, "animation": 1, "comedy": 1}
+			if !maps.Equal(genres, wantGenres) {
+				t.Errorf("genres: got %v, want %v", genres, wantGenres)
+			}
+		})
+	})
+}
+
+func movieTitles(movies []*Movie) []string {
+	titles := make([]string, len(movies))
+	for i, movie := range movies {
+		titles[i] = movie.Title
+	}
+	return titles
+}
+
+func TestPeopleNameFilter(t *testing.T) {
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"", []string{"Ali_ce", "Alice", "Bob 100%"}},
+		{"ali", []string{"Ali_ce", "Alice"}},
+		{"i_", []string{"Ali_ce"}},
+		{"100%", []string{"Bob 100%"}},
+		{"%", []string{"Bob 100%"}},
+	}
+	eachBackend(t, func(t *testing.T, models *Models) {
+		ctx := context.Background()
+		for _, name := range []string{"Ali_ce", "Alice", "Bob 100%"} {
+			if err := models.People.Insert(ctx, &Person{Name: name}); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		for _, tt := range tests {
+			filter := Filters{Page: 1, PageSize: 20, Sort: "id", SortSafelist: []string{"id"}}
+			people, _, err := models.People.GetAll(ctx, tt.name, filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, person := range people {
+				names = append(names, person.Name)
+			}
+			if !slices.Equal(names, tt.want) {
+				t.Errorf("name %q: got %q, want %q", tt.name, names, tt.want)
+			}
+		}
+	})
+}
@@ -0,0 +1,37 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/arian-nj/site/back/internal/validator"
+)
+
+var ErrDuplicateReview = errors.New("duplicate review")
+
+// Review is a single user's rating of a movie. A user has at most one
+// review per movie.
+type Review struct {
+	UserID    int64     `json:"user_id"`
+	UserName  string    `json:"user_name"`
+	MovieID   int64     `json:"movie_id"`
+	Score     int32     `json:"score"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+	Version   int32     `json:"version"`
+}
+
+type ReviewRepository interface {
+	Insert(ctx context.Context, review *Review) error
+	Get(ctx context.Context, userID, movieID int64) (*Review, error)
+	Update(ctx context.Context, review *Review) error
+	Delete(ctx context.Context, userID, movieID int64) error
+	GetAllForMovie(ctx context.Context, movieID int64, filter Filters) ([]*Review, Metadata, error)
+}
+
+func ValidateReview(v *validator.Validator, review *Review) {
+	v.Check(review.Score >= 1, "score", "must be at least 1")
+	v.Check(review.Score <= 10, "score", "must not be more than 10")
+	v.Check(len(review.Body) <= 10_000, "body", "must not be more than 10000 bytes long")
+}
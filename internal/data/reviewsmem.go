@@ -0,0 +1,124 @@
+package data
+
+import (
+	"cmp"
+	"context"
+	"math"
+	"slices"
+)
+
+type reviewMemModel struct {
+	s *memoryStore
+}
+
+// review() returns a copy of a stored review with the reviewer's name
+// filled in.
+func (s *memoryStore) review(stored *Review) *Review {
+	review := *stored
+	if user, ok := s.users[review.UserID]; ok {
+		review.UserName = user.Name
+	}
+	return &review
+}
+
+// refreshRating() recomputes the rating summary of a movie, rounding the
+// average like the numeric(4,2) column does.
+func (s *memoryStore) refreshRating(movie *Movie) {
+	var total, count int32
+	for key, review := range s.reviews {
+		if key.movieID == movie.ID {
+			total += review.Score
+			count++
+		}
+	}
+	movie.RatingCount = count
+	movie.AverageRating = 0
+	if count > 0 {
+		movie.AverageRating = math.Round(float64(total)/float64(count)*100) / 100
+	}
+}
+
+func (m reviewMemModel) Insert(ctx context.Context, review *Review) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	movie, ok := m.s.live(review.MovieID)
+	if !ok {
+		return ErrRecordNotFound
+	}
+	key := memReviewKey{review.UserID, review.MovieID}
+	if _, ok := m.s.reviews[key]; ok {
+		return ErrDuplicateReview
+	}
+	review.CreatedAt = now()
+	review.Version = 1
+	stored := *review
+	m.s.reviews[key] = &stored
+	m.s.refreshRating(movie)
+	return nil
+}
+
+func (m reviewMemModel) Get(ctx context.Context, userID, movieID int64) (*Review, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	stored, ok := m.s.reviews[memReviewKey{userID, movieID}]
+	if !ok {
+		return nil, ErrRecordNotFound
+	}
+	return m.s.review(stored), nil
+}
+
+func (m reviewMemModel) Update(ctx context.Context, review *Review) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	movie, ok := m.s.live(review.MovieID)
+	if !ok {
+		return ErrRecordNotFound
+	}
+	stored, ok := m.s.reviews[memReviewKey{review.UserID, review.MovieID}]
+	if !ok || stored.Version != review.Version {
+		return ErrEditConflict
+	}
+	stored.Score = review.Score
+	stored.Body = review.Body
+	stored.Version++
+	review.Version = stored.Version
+	m.s.refreshRating(movie)
+	return nil
+}
+
+func (m reviewMemModel) Delete(ctx context.Context, userID, movieID int64) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	movie, ok := m.s.live(movieID)
+	if !ok {
+		return ErrRecordNotFound
+	}
+	key := memReviewKey{userID, movieID}
+	if _, ok := m.s.reviews[key]; !ok {
+		return ErrRecordNotFound
+	}
+	delete(m.s.reviews, key)
+	m.s.refreshRating(movie)
+	return nil
+}
+
+func (m reviewMemModel) GetAllForMovie(ctx context.Context, movieID int64, filter Filters) ([]*Review, Metadata, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	reviews := []*Review{}
+	for key, stored := range m.s.reviews {
+		if key.movieID == movieID {
+			reviews = append(reviews, m.s.review(stored))
+		}
+	}
+
+	column := func(a, b *Review) int { return a.CreatedAt.Compare(b.CreatedAt) }
+	if filter.sortColumn() == "score" {
+		column = func(a, b *Review) int { return cmp.Compare(a.Score, b.Score) }
+	}
+	slices.SortFunc(reviews, orderBy(filter, column, func(a, b *Review) int {
+		return cmp.Compare(a.UserID, b.UserID)
+	}))
+	reviews, metadata := paginate(reviews, filter)
+	return reviews, metadata, nil
+}
@@ -0,0 +1,181 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// reviewSQLiteModel is the SQLite ReviewRepository.
+type reviewSQLiteModel struct {
+	DB      DBTX
+	Timeout time.Duration
+}
+
+// Insert() adds a review and refreshes the rating summary of its movie in
+// the same transaction.
+func (m reviewSQLiteModel) Insert(ctx context.Context, review *Review) error {
+	query := `
+	INSERT INTO reviews (user_id, movie_id, score, body)
+	VALUES ($1, $2, $3, $4)
+	RETURNING created_at, version`
+
+	return m.withRatingRefresh(ctx, review.MovieID, func(ctx context.Context, tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, query, review.UserID, review.MovieID, review.Score, review.Body).
+			Scan(&review.CreatedAt, &review.Version)
+		if isUniqueViolation(err) {
+			return ErrDuplicateReview
+		}
+		return err
+	})
+}
+
+func (m reviewSQLiteModel) Get(ctx context.Context, userID, movieID int64) (*Review, error) {
+	query := `
+	SELECT reviews.user_id, users.name, reviews.movie_id, reviews.score, reviews.body,
+	reviews.created_at, reviews.version
+	FROM reviews
+	INNER JOIN users ON users.id = reviews.user_id
+	WHERE reviews.user_id = $1 AND reviews.movie_id = $2`
+
+	var review Review
+	ctx, cancel := context.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+	err := m.DB.QueryRowContext(ctx, query, userID, movieID).Scan(
+		&review.UserID,
+		&review.UserName,
+		&review.MovieID,
+		&review.Score,
+		&review.Body,
+		&review.CreatedAt,
+		&review.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &review, nil
+}
+
+func (m reviewSQLiteModel) Update(ctx context.Context, review *Review) error {
+	query := `
+	UPDATE reviews
+	SET score = $1, body = $2, version = version + 1
+	WHERE user_id = $3 AND movie_id = $4 AND version = $5
+	RETURNING version`
+
+	return m.withRatingRefresh(ctx, review.MovieID, func(ctx context.Context, tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, query, review.Score, review.Body, review.UserID, review.MovieID, review.Version).
+			Scan(&review.Version)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrEditConflict
+		}
+		return err
+	})
+}
+
+func (m reviewSQLiteModel) Delete(ctx context.Context, userID, movieID int64) error {
+	query := `
+	DELETE FROM reviews
+	WHERE user_id = $1 AND movie_id = $2`
+
+	return m.withRatingRefresh(ctx, movieID, func(ctx context.Context, tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, query, userID, movieID)
+		if err != nil {
+			return err
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+func (m reviewSQLiteModel) GetAllForMovie(ctx context.Context, movieID int64, filter Filters) ([]*Review, Metadata, error) {
+	query := fmt.Sprintf(`
+	SELECT count(*) OVER(), reviews.user_id, users.name, reviews.movie_id, reviews.score,
+	reviews.body, reviews.created_at, reviews.version
+	FROM reviews
+	INNER JOIN users ON users.id = reviews.user_id
+	WHERE reviews.movie_id = $1
+	ORDER BY reviews.%s %s, reviews.user_id ASC LIMIT $2 OFFSET $3`, filter.sortColumn(), filter.sortDirection())
+
+	ctx, cancel := context.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+	rows, err := m.DB.QueryContext(ctx, query, movieID, filter.PageSize, (filter.Page-1)*filter.PageSize)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	reviews := []*Review{}
+	for rows.Next() {
+		var review Review
+		err := rows.Scan(
+			&totalRecords,
+			&review.UserID,
+			&review.UserName,
+			&review.MovieID,
+			&review.Score,
+			&review.Body,
+			&review.CreatedAt,
+			&review.Version,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		reviews = append(reviews, &review)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+	return reviews, calculateMetadata(totalRecords, filter.Page, filter.PageSize), nil
+}
+
+// withRatingRefresh() runs fn in a transaction and then recomputes the
+// average_rating and rating_count columns of the movie before committing.
+func (m reviewSQLiteModel) withRatingRefresh(ctx context.Context, movieID int64, fn func(ctx context.Context, tx *sql.Tx) error) error {
+	query := `
+	UPDATE movies
+	SET average_rating = COALESCE((SELECT round(avg(score), 2) FROM reviews WHERE movie_id = $1), 0),
+	rating_count = (SELECT count(*) FROM reviews WHERE movie_id = $1)
+	WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+	tx, err := beginTx(ctx, m.DB, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// The transaction holds the database write lock from its start, which
+	// serialises concurrent review changes like FOR UPDATE does.
+	var id int64
+	err = tx.QueryRowContext(ctx, `SELECT id FROM movies WHERE id = $1 AND deleted_at IS NULL`, movieID).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrRecordNotFound
+		}
+		return err
+	}
+
+	if err := fn(ctx, tx.Tx); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, query, movieID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
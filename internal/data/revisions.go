@@ -0,0 +1,24 @@
+package data
+
+import (
+	"context"
+	"time"
+)
+
+// Revision is a movie as it was at a given version. EditedBy is the user
+// whose update replaced it; it is nil once that user has been deleted.
+type Revision struct {
+	MovieID    int64     `json:"movie_id"`
+	Version    int32     `json:"version"`
+	Title      string    `json:"title"`
+	Year       int32     `json:"year"`
+	Runtime    Runtime   `json:"runtime"`
+	Genres     []string  `json:"genres"`
+	EditedBy   *int64    `json:"edited_by"`
+	ReplacedAt time.Time `json:"replaced_at"`
+}
+
+type RevisionRepository interface {
+	GetAllForMovie(ctx context.Context, movieID int64) ([]*Revision, error)
+	Get(ctx context.Context, movieID int64, version int32) (*Revision, error)
+}
@@ -0,0 +1,41 @@
+package data
+
+import (
+	"cmp"
+	"context"
+	"slices"
+)
+
+type revisionMemModel struct {
+	s *memoryStore
+}
+
+func cloneRevision(revision *Revision) *Revision {
+	clone := *revision
+	clone.Genres = slices.Clone(revision.Genres)
+	return &clone
+}
+
+func (m revisionMemModel) GetAllForMovie(ctx context.Context, movieID int64) ([]*Revision, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	revisions := []*Revision{}
+	for _, revision := range m.s.revisions[movieID] {
+		revisions = append(revisions, cloneRevision(revision))
+	}
+	slices.SortFunc(revisions, func(a, b *Revision) int {
+		return cmp.Compare(b.Version, a.Version)
+	})
+	return revisions, nil
+}
+
+func (m revisionMemModel) Get(ctx context.Context, movieID int64, version int32) (*Revision, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	for _, revision := range m.s.revisions[movieID] {
+		if revision.Version == version {
+			return cloneRevision(revision), nil
+		}
+	}
+	return nil, ErrRecordNotFound
+}
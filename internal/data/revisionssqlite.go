@@ -0,0 +1,87 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// revisionSQLiteModel is the SQLite RevisionRepository.
+type revisionSQLiteModel struct {
+	DB      DBTX
+	Timeout time.Duration
+}
+
+// GetAllForMovie() returns every stored revision of a movie, newest first.
+func (m revisionSQLiteModel) GetAllForMovie(ctx context.Context, movieID int64) ([]*Revision, error) {
+	query := `
+	SELECT movie_id, version, title, year, runtime, genres, edited_by, replaced_at
+	FROM movie_revisions
+	WHERE movie_id = $1
+	ORDER BY version DESC`
+
+	ctx, cancel := context.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+	rows, err := m.DB.QueryContext(ctx, query, movieID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []*Revision{}
+	for rows.Next() {
+		var revision Revision
+		err := rows.Scan(
+			&revision.MovieID,
+			&revision.Version,
+			&revision.Title,
+			&revision.Year,
+			&revision.Runtime,
+			jsonArray{&revision.Genres},
+			&revision.EditedBy,
+			&revision.ReplacedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, &revision)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+func (m revisionSQLiteModel) Get(ctx context.Context, movieID int64, version int32) (*Revision, error) {
+	if movieID < 1 || version < 1 {
+		return nil, ErrRecordNotFound
+	}
+	query := `
+	SELECT movie_id, version, title, year, runtime, genres, edited_by, replaced_at
+	FROM movie_revisions
+	WHERE movie_id = $1 AND version = $2`
+
+	var revision Revision
+	ctx, cancel := context.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+	err := m.DB.QueryRowContext(ctx, query, movieID, version).Scan(
+		&revision.MovieID,
+		&revision.Version,
+		&revision.Title,
+		&revision.Year,
+		&revision.Runtime,
+		jsonArray{&revision.Genres},
+		&revision.EditedBy,
+		&revision.ReplacedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &revision, nil
+}
@@ -0,0 +1,110 @@
+package data
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"modernc.org/sqlite"
+)
+
+func init() {
+	// word_similarity() stands in for the pg_trgm function of the same name
+	// in the SQLite title suggestions.
+	sqlite.MustRegisterDeterministicScalarFunction("word_similarity", 2,
+		func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+			q, _ := args[0].(string)
+			text, _ := args[1].(string)
+			return wordSimilarity(q, text), nil
+		})
+}
+
+// sqliteDSN() adds the connection settings the SQLite models rely on to
+// dsn: enforced foreign keys, waiting on a busy database rather than
+// failing, WAL so readers don't block the writer, and transactions that take
+// the write lock up front in place of the row locks used with PostgreSQL.
+func sqliteDSN(dsn string) string {
+	separator := "?"
+	if strings.Contains(dsn, "?") {
+		separator = "&"
+	}
+	return dsn + separator + "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)" +
+		"&_pragma=journal_mode(WAL)&_txlock=immediate"
+}
+
+// NewSQLiteModels() returns Models backed by the SQLite database conn, which
+// must have been opened by OpenDB(). Each query is bounded by queryTimeout
+// on top of the caller's context.
+func NewSQLiteModels(conn *sql.DB, queryTimeout time.Duration) *Models {
+	models := newSQLiteModels(conn, queryTimeout)
+	models.withTx = sqlWithTx(conn, func(tx DBTX) *Models {
+		return newSQLiteModels(tx, queryTimeout)
+	})
+	return models
+}
+
+func newSQLiteModels(conn DBTX, queryTimeout time.Duration) *Models {
+	return &Models{
+		Movie:       movieSQLiteModel{DB: conn, Timeout: queryTimeout},
+		Users:       userSQLiteModel{DB: conn, Timeout: queryTimeout},
+		Token:       tokenSQLiteModel{DB: conn, Timeout: queryTimeout},
+		Permissions: permissionSQLiteModel{DB: conn, Timeout: queryTimeout},
+		Revisions:   revisionSQLiteModel{DB: conn, Timeout: queryTimeout},
+		Reviews:     reviewSQLiteModel{DB: conn, Timeout: queryTimeout},
+		People:      personSQLiteModel{DB: conn, Timeout: queryTimeout},
+		Credits:     creditSQLiteModel{DB: conn, Timeout: queryTimeout},
+		Lists:       listSQLiteModel{DB: conn, Timeout: queryTimeout},
+	}
+}
+
+// sqliteTimeFormat is how timestamps are stored in SQLite: UTC text that
+// sorts chronologically, matching the column defaults of the migrations.
+// The driver parses it back into time.Time for columns declared timestamp.
+const sqliteTimeFormat = "2006-01-02 15:04:05-07:00"
+
+// sqliteTime() formats t for storing in, or comparing with, a timestamp
+// column.
+func sqliteTime(t time.Time) string {
+	return t.UTC().Format(sqliteTimeFormat)
+}
+
+// jsonArray stores a []string as a JSON array in a text column, in place of
+// the text[] columns of PostgreSQL. Like pq.Array(), it wraps a pointer so
+// it can be both an argument and a scan destination.
+type jsonArray struct {
+	a *[]string
+}
+
+func (j jsonArray) Value() (driver.Value, error) {
+	if *j.a == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal(*j.a)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (j jsonArray) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case string:
+		return json.Unmarshal([]byte(src), j.a)
+	case []byte:
+		return json.Unmarshal(src, j.a)
+	}
+	return fmt.Errorf("cannot scan %T into a string array", src)
+}
+
+// sqliteNulls() returns the NULLS clause that sorts NULL like PostgreSQL
+// does: after every value in ascending order and before them in descending
+// order.
+func sqliteNulls(direction string) string {
+	if direction == "DESC" {
+		return "NULLS FIRST"
+	}
+	return "NULLS LAST"
+}
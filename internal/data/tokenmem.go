@@ -0,0 +1,96 @@
+package data
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"slices"
+	"time"
+)
+
+// tokenMemModel keeps tokens keyed by their hash, so plaintext tokens are
+// never held in memory any longer than the PostgreSQL backend holds them.
+type tokenMemModel struct {
+	s *memoryStore
+}
+
+func (m tokenMemModel) New(ctx context.Context, userID int64, ttl time.Duration, scope string) (*Token, error) {
+	token, err := generateToken(userID, ttl, scope)
+	if err != nil {
+		return nil, err
+	}
+	err = m.Insert(ctx, token)
+	return token, err
+}
+
+func (m tokenMemModel) NewSession(ctx context.Context, userID int64, ttl time.Duration, userAgent, ip string) (*Token, error) {
+	token, err := generateToken(userID, ttl, ScopeAuthentication)
+	if err != nil {
+		return nil, err
+	}
+	token.UserAgent = userAgent
+	token.IP = ip
+	err = m.Insert(ctx, token)
+	return token, err
+}
+
+func (m tokenMemModel) Insert(ctx context.Context, token *Token) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	if _, ok := m.s.users[token.UserID]; !ok {
+		return ErrRecordNotFound
+	}
+	stored := *token
+	stored.Plaintext = ""
+	stored.Hash = slices.Clone(token.Hash)
+	stored.CreatedAt = token.CreatedAt.Truncate(time.Second)
+	stored.Expiry = token.Expiry.Truncate(time.Second)
+	m.s.tokens[string(token.Hash)] = &stored
+	return nil
+}
+
+func (m tokenMemModel) DeleteAllForUser(ctx context.Context, scope string, userID int64) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	for hash, token := range m.s.tokens {
+		if token.Scope == scope && token.UserID == userID {
+			delete(m.s.tokens, hash)
+		}
+	}
+	return nil
+}
+
+func (m tokenMemModel) DeleteByPlaintext(ctx context.Context, scope, tokenPlainText string) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	tokenHash := sha256.Sum256([]byte(tokenPlainText))
+	token, ok := m.s.tokens[string(tokenHash[:])]
+	if !ok || token.Scope != scope {
+		return ErrRecordNotFound
+	}
+	delete(m.s.tokens, string(tokenHash[:]))
+	return nil
+}
+
+func (m tokenMemModel) GetSessionsForUser(ctx context.Context, userID int64, currentPlainText string) ([]*Session, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	currentHash := sha256.Sum256([]byte(currentPlainText))
+	sessions := []*Session{}
+	for hash, token := range m.s.tokens {
+		if token.Scope != ScopeAuthentication || token.UserID != userID || !token.Expiry.After(time.Now()) {
+			continue
+		}
+		sessions = append(sessions, &Session{
+			CreatedAt: token.CreatedAt,
+			Expiry:    token.Expiry,
+			UserAgent: token.UserAgent,
+			IP:        token.IP,
+			Current:   hash == string(currentHash[:]),
+		})
+	}
+	slices.SortFunc(sessions, func(a, b *Session) int {
+		return cmp.Compare(b.CreatedAt.UnixNano(), a.CreatedAt.UnixNano())
+	})
+	return sessions, nil
+}
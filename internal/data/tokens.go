@@ -1,6 +1,7 @@
 package data
 
 import (
+	"context"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base32"
@@ -12,21 +13,45 @@ import (
 const (
 	ScopeActivation     = "activation"
 	ScopeAuthentication = "authentication"
+	ScopePasswordReset  = "password-reset"
 )
 
 type Token struct {
 	Plaintext string    `json:"token"`
 	Hash      []byte    `json:"-"`
 	UserID    int64     `json:"-"`
+	CreatedAt time.Time `json:"-"`
 	Expiry    time.Time `json:"expiry"`
 	Scope     string    `json:"-"`
+	UserAgent string    `json:"-"`
+	IP        string    `json:"-"`
+}
+
+// Session describes a live authentication token without exposing the token
+// itself.
+type Session struct {
+	CreatedAt time.Time `json:"created_at"`
+	Expiry    time.Time `json:"expiry"`
+	UserAgent string    `json:"user_agent"`
+	IP        string    `json:"ip"`
+	Current   bool      `json:"current"`
+}
+
+type TokenRepository interface {
+	New(ctx context.Context, userID int64, ttl time.Duration, scope string) (*Token, error)
+	NewSession(ctx context.Context, userID int64, ttl time.Duration, userAgent, ip string) (*Token, error)
+	Insert(ctx context.Context, token *Token) error
+	DeleteAllForUser(ctx context.Context, scope string, userID int64) error
+	DeleteByPlaintext(ctx context.Context, scope, tokenPlainText string) error
+	GetSessionsForUser(ctx context.Context, userID int64, currentPlainText string) ([]*Session, error)
 }
 
 func generateToken(UserId int64, ttl time.Duration, scope string) (*Token, error) {
 	token := &Token{
-		UserID: UserId,
-		Expiry: time.Now().Add(ttl),
-		Scope:  scope,
+		UserID:    UserId,
+		CreatedAt: time.Now(),
+		Expiry:    time.Now().Add(ttl),
+		Scope:     scope,
 	}
 	randomBytes := make([]byte, 16)
 	_, err := rand.Read(randomBytes)
@@ -0,0 +1,116 @@
+package data
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"time"
+)
+
+// tokenSQLiteModel is the SQLite TokenRepository.
+type tokenSQLiteModel struct {
+	DB      DBTX
+	Timeout time.Duration
+}
+
+func (m tokenSQLiteModel) New(ctx context.Context, userID int64, ttl time.Duration, scope string) (*Token, error) {
+	token, err := generateToken(userID, ttl, scope)
+	if err != nil {
+		return nil, err
+	}
+	err = m.Insert(ctx, token)
+	return token, err
+}
+
+// NewSession() issues an authentication token, recording the client it was
+// issued to.
+func (m tokenSQLiteModel) NewSession(ctx context.Context, userID int64, ttl time.Duration, userAgent, ip string) (*Token, error) {
+	token, err := generateToken(userID, ttl, ScopeAuthentication)
+	if err != nil {
+		return nil, err
+	}
+	token.UserAgent = userAgent
+	token.IP = ip
+	err = m.Insert(ctx, token)
+	return token, err
+}
+
+// Insert() adds the data for a specific token to the tokens table.
+func (m tokenSQLiteModel) Insert(ctx context.Context, token *Token) error {
+	query := `
+INSERT INTO tokens (hash, user_id, created_at, expiry, scope, user_agent, ip)
+VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	args := []interface{}{token.Hash, token.UserID, sqliteTime(token.CreatedAt), sqliteTime(token.Expiry),
+		token.Scope, token.UserAgent, token.IP}
+	ctx, cancel := context.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+	_, err := m.DB.ExecContext(ctx, query, args...)
+	return err
+}
+
+// DeleteAllForUser() deletes all tokens for a specific user and scope.
+func (m tokenSQLiteModel) DeleteAllForUser(ctx context.Context, scope string, userID int64) error {
+	query := `
+DELETE FROM tokens
+WHERE scope = $1 AND user_id = $2`
+	ctx, cancel := context.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+	_, err := m.DB.ExecContext(ctx, query, scope, userID)
+	return err
+}
+
+// DeleteByPlaintext() revokes a single token.
+func (m tokenSQLiteModel) DeleteByPlaintext(ctx context.Context, scope, tokenPlainText string) error {
+	tokenHash := sha256.Sum256([]byte(tokenPlainText))
+	query := `
+DELETE FROM tokens
+WHERE scope = $1 AND hash = $2`
+	ctx, cancel := context.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+	result, err := m.DB.ExecContext(ctx, query, scope, tokenHash[:])
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// GetSessionsForUser() lists the unexpired authentication tokens of a user,
+// newest first. currentPlainText marks the token making the request.
+func (m tokenSQLiteModel) GetSessionsForUser(ctx context.Context, userID int64, currentPlainText string) ([]*Session, error) {
+	currentHash := sha256.Sum256([]byte(currentPlainText))
+	query := `
+SELECT hash, created_at, expiry, user_agent, ip
+FROM tokens
+WHERE scope = $1 AND user_id = $2 AND expiry > $3
+ORDER BY created_at DESC`
+	ctx, cancel := context.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+	rows, err := m.DB.QueryContext(ctx, query, ScopeAuthentication, userID, sqliteTime(time.Now()))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []*Session{}
+	for rows.Next() {
+		var hash []byte
+		var session Session
+		err := rows.Scan(&hash, &session.CreatedAt, &session.Expiry, &session.UserAgent, &session.IP)
+		if err != nil {
+			return nil, err
+		}
+		session.Current = bytes.Equal(hash, currentHash[:])
+		sessions = append(sessions, &session)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
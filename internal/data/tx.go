@@ -0,0 +1,90 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+)
+
+// DBTX is what the SQL models query through: the connection pool,
+// or a transaction when the models were handed out by WithTx().
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+// WithTx() runs fn as one unit of work. The models passed to fn share a
+// single transaction, which is committed when fn returns nil and rolled
+// back otherwise. fn must only use tx; calling WithTx() on tx joins the
+// transaction already in progress.
+func (m *Models) WithTx(ctx context.Context, fn func(tx *Models) error) error {
+	return m.withTx(ctx, fn)
+}
+
+// sqlWithTx() implements WithTx() for models over the pool conn. build
+// returns the models bound to a transaction; those join it when WithTx() is
+// called on them again.
+func sqlWithTx(conn *sql.DB, build func(tx DBTX) *Models) func(ctx context.Context, fn func(tx *Models) error) error {
+	return func(ctx context.Context, fn func(tx *Models) error) error {
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		txModels := build(tx)
+		txModels.withTx = func(_ context.Context, fn func(tx *Models) error) error {
+			return fn(txModels)
+		}
+		if err := fn(txModels); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+}
+
+// modelTx is a transaction begun by a single model method. Inside a
+// WithTx() unit it is a savepoint of the enclosing transaction instead, so
+// the method still succeeds or fails as a whole without ending the unit.
+type modelTx struct {
+	*sql.Tx
+	savepoint bool
+	done      bool
+}
+
+func beginTx(ctx context.Context, conn DBTX, opts *sql.TxOptions) (*modelTx, error) {
+	if tx, ok := conn.(*sql.Tx); ok {
+		_, err := tx.ExecContext(ctx, `SAVEPOINT model_tx`)
+		if err != nil {
+			return nil, err
+		}
+		return &modelTx{Tx: tx, savepoint: true}, nil
+	}
+	tx, err := conn.(*sql.DB).BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &modelTx{Tx: tx}, nil
+}
+
+func (t *modelTx) Commit() error {
+	if !t.savepoint {
+		return t.Tx.Commit()
+	}
+	t.done = true
+	_, err := t.Tx.Exec(`RELEASE SAVEPOINT model_tx`)
+	return err
+}
+
+func (t *modelTx) Rollback() error {
+	if !t.savepoint {
+		return t.Tx.Rollback()
+	}
+	if t.done {
+		return nil
+	}
+	t.done = true
+	_, err := t.Tx.Exec(`ROLLBACK TO SAVEPOINT model_tx`)
+	return err
+}
@@ -1,6 +1,7 @@
 package data
 
 import (
+	"context"
 	"errors"
 	"time"
 
@@ -20,6 +21,13 @@ type User struct {
 	Version   int       `json:"-"`
 }
 
+type UserRepository interface {
+	Insert(ctx context.Context, user *User) error
+	GetByEmail(ctx context.Context, email string) (*User, error)
+	Update(ctx context.Context, user *User) error
+	GetForToken(ctx context.Context, tokenScope, tokenPlainText string) (*User, error)
+}
+
 func (u *User) IsAnonymous() bool {
 	return u == AnonymousUser
 
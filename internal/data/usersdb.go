@@ -13,24 +13,25 @@ var (
 )
 
 type UserModel struct {
-	DB *sql.DB
+	DB      DBTX
+	Timeout time.Duration
 }
 
-func (m UserModel) Insert(user *User) error {
+func (m UserModel) Insert(ctx context.Context, user *User) error {
 	query := `
 INSERT INTO users (name, email, password_hash, activated)
 VALUES ($1, $2, $3, $4)
 RETURNING id, created_at, version`
 	args := []interface{}{user.Name, user.Email, user.Password.Hash,
 		user.Activated}
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, m.Timeout)
 	defer cancel()
 
 	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&user.ID,
 		&user.CreatedAt, &user.Version)
 	if err != nil {
 		switch {
-		case err.Error() == `pq: duplicate key value violates unique constraint "users_email_key"`:
+		case isUniqueViolation(err):
 			return ErrDuplicateEmail
 		default:
 			return err
@@ -39,13 +40,13 @@ RETURNING id, created_at, version`
 	return nil
 }
 
-func (m UserModel) GetByEmail(email string) (*User, error) {
+func (m UserModel) GetByEmail(ctx context.Context, email string) (*User, error) {
 	query := `
 SELECT id, created_at, name, email, password_hash, activated, version
 FROM users
 WHERE email = $1`
 	var user User
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, m.Timeout)
 	defer cancel()
 	err := m.DB.QueryRowContext(ctx, query, email).Scan(
 		&user.ID,
@@ -67,7 +68,7 @@ WHERE email = $1`
 	return &user, nil
 }
 
-func (m UserModel) Update(user *User) error {
+func (m UserModel) Update(ctx context.Context, user *User) error {
 	query := `
 UPDATE users
 SET name = $1, email = $2, password_hash = $3, activated = $4,
@@ -82,13 +83,12 @@ RETURNING version`
 		user.ID,
 		user.Version,
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, m.Timeout)
 	defer cancel()
 	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&user.Version)
 	if err != nil {
 		switch {
-		case err.Error() == `pq: duplicate key value violates unique
-constraint "users_email_key"`:
+		case isUniqueViolation(err):
 			return ErrDuplicateEmail
 		case errors.Is(err, sql.ErrNoRows):
 			return ErrEditConflict
@@ -99,7 +99,7 @@ constraint "users_email_key"`:
 	return nil
 }
 
-func (m UserModel) GetForToken(tokenScope, tokenPlainText string) (*User, error) {
+func (m UserModel) GetForToken(ctx context.Context, tokenScope, tokenPlainText string) (*User, error) {
 
 	tokenHash := sha256.Sum256([]byte(tokenPlainText))
 	query := `
@@ -115,7 +115,7 @@ func (m UserModel) GetForToken(tokenScope, tokenPlainText string) (*User, error)
 	args := []interface{}{tokenHash[:], tokenScope, time.Now()}
 	var user User
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, m.Timeout)
 	defer cancel()
 	err := m.DB.QueryRowContext(ctx, query, args...).Scan(
 		&user.ID,
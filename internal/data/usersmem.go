@@ -0,0 +1,84 @@
+package data
+
+import (
+	"context"
+	"crypto/sha256"
+	"slices"
+	"strings"
+	"time"
+)
+
+type userMemModel struct {
+	s *memoryStore
+}
+
+func cloneUser(user *User) *User {
+	clone := *user
+	clone.Password = Password{Hash: slices.Clone(user.Password.Hash)}
+	return &clone
+}
+
+// emailTaken() reports whether another user has the address. Emails are
+// compared case-insensitively, like the citext column.
+func (s *memoryStore) emailTaken(email string, exceptID int64) bool {
+	for _, user := range s.users {
+		if user.ID != exceptID && strings.EqualFold(user.Email, email) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m userMemModel) Insert(ctx context.Context, user *User) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	if m.s.emailTaken(user.Email, 0) {
+		return ErrDuplicateEmail
+	}
+	user.ID = m.s.nextID("users")
+	user.CreatedAt = now()
+	user.Version = 1
+	m.s.users[user.ID] = cloneUser(user)
+	return nil
+}
+
+func (m userMemModel) GetByEmail(ctx context.Context, email string) (*User, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	for _, user := range m.s.users {
+		if strings.EqualFold(user.Email, email) {
+			return cloneUser(user), nil
+		}
+	}
+	return nil, ErrRecordNotFound
+}
+
+func (m userMemModel) Update(ctx context.Context, user *User) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	stored, ok := m.s.users[user.ID]
+	if !ok || stored.Version != user.Version {
+		return ErrEditConflict
+	}
+	if m.s.emailTaken(user.Email, user.ID) {
+		return ErrDuplicateEmail
+	}
+	user.Version++
+	m.s.users[user.ID] = cloneUser(user)
+	return nil
+}
+
+func (m userMemModel) GetForToken(ctx context.Context, tokenScope, tokenPlainText string) (*User, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	tokenHash := sha256.Sum256([]byte(tokenPlainText))
+	token, ok := m.s.tokens[string(tokenHash[:])]
+	if !ok || token.Scope != tokenScope || !token.Expiry.After(time.Now()) {
+		return nil, ErrRecordNotFound
+	}
+	user, ok := m.s.users[token.UserID]
+	if !ok {
+		return nil, ErrRecordNotFound
+	}
+	return cloneUser(user), nil
+}
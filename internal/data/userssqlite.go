@@ -0,0 +1,136 @@
+package data
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// userSQLiteModel is the SQLite UserRepository. The email column is
+// declared COLLATE NOCASE, which takes the place of citext.
+type userSQLiteModel struct {
+	DB      DBTX
+	Timeout time.Duration
+}
+
+func (m userSQLiteModel) Insert(ctx context.Context, user *User) error {
+	query := `
+INSERT INTO users (name, email, password_hash, activated)
+VALUES ($1, $2, $3, $4)
+RETURNING id, created_at, version`
+	args := []interface{}{user.Name, user.Email, user.Password.Hash,
+		user.Activated}
+	ctx, cancel := context.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&user.ID,
+		&user.CreatedAt, &user.Version)
+	if err != nil {
+		switch {
+		case isUniqueViolation(err):
+			return ErrDuplicateEmail
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+func (m userSQLiteModel) GetByEmail(ctx context.Context, email string) (*User, error) {
+	query := `
+SELECT id, created_at, name, email, password_hash, activated, version
+FROM users
+WHERE email = $1`
+	var user User
+	ctx, cancel := context.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+	err := m.DB.QueryRowContext(ctx, query, email).Scan(
+		&user.ID,
+		&user.CreatedAt,
+		&user.Name,
+		&user.Email,
+		&user.Password.Hash,
+		&user.Activated,
+		&user.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &user, nil
+}
+
+func (m userSQLiteModel) Update(ctx context.Context, user *User) error {
+	query := `
+UPDATE users
+SET name = $1, email = $2, password_hash = $3, activated = $4,
+version = version + 1
+WHERE id = $5 AND version = $6
+RETURNING version`
+	args := []interface{}{
+		user.Name,
+		user.Email,
+		user.Password.Hash,
+		user.Activated,
+		user.ID,
+		user.Version,
+	}
+	ctx, cancel := context.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&user.Version)
+	if err != nil {
+		switch {
+		case isUniqueViolation(err):
+			return ErrDuplicateEmail
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+func (m userSQLiteModel) GetForToken(ctx context.Context, tokenScope, tokenPlainText string) (*User, error) {
+
+	tokenHash := sha256.Sum256([]byte(tokenPlainText))
+	query := `
+		SELECT users.id, users.created_at, users.name, users.email,
+		users.password_hash, users.activated, users.version
+		FROM users
+		INNER JOIN tokens
+		ON users.id = tokens.user_id
+		WHERE tokens.hash = $1
+		AND tokens.scope = $2
+		AND tokens.expiry > $3`
+
+	args := []interface{}{tokenHash[:], tokenScope, sqliteTime(time.Now())}
+	var user User
+
+	ctx, cancel := context.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(
+		&user.ID,
+		&user.CreatedAt,
+		&user.Name,
+		&user.Email,
+		&user.Password.Hash,
+		&user.Activated,
+		&user.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &user, nil
+}
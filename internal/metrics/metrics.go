@@ -0,0 +1,191 @@
+// Package metrics implements the small subset of Prometheus instruments the
+// api needs, rendered in the Prometheus text exposition format.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are latency buckets in seconds, matching the Prometheus
+// client defaults.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+type CounterVec struct {
+	name   string
+	help   string
+	labels []string
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{
+		name:   name,
+		help:   help,
+		labels: labels,
+		values: make(map[string]float64),
+	}
+}
+
+// Inc() adds one to the counter identified by the label values, which must
+// be given in the order the labels were declared.
+func (c *CounterVec) Inc(labelValues ...string) {
+	key := labelKey(c.labels, labelValues)
+	c.mu.Lock()
+	c.values[key]++
+	c.mu.Unlock()
+}
+
+func (c *CounterVec) WriteTo(w io.Writer) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var b strings.Builder
+	writeHeader(&b, c.name, c.help, "counter")
+	for _, key := range sortedKeys(c.values) {
+		fmt.Fprintf(&b, "%s%s %s\n", c.name, key, formatFloat(c.values[key]))
+	}
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+type HistogramVec struct {
+	name    string
+	help    string
+	labels  []string
+	buckets []float64
+	mu      sync.Mutex
+	values  map[string]*histogram
+}
+
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	return &HistogramVec{
+		name:    name,
+		help:    help,
+		labels:  labels,
+		buckets: buckets,
+		values:  make(map[string]*histogram),
+	}
+}
+
+// Observe() records v in the histogram identified by the label values.
+func (h *HistogramVec) Observe(v float64, labelValues ...string) {
+	key := labelKey(h.labels, labelValues)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	hist, ok := h.values[key]
+	if !ok {
+		hist = &histogram{counts: make([]uint64, len(h.buckets))}
+		h.values[key] = hist
+	}
+	for i, upper := range h.buckets {
+		if v <= upper {
+			hist.counts[i]++
+		}
+	}
+	hist.sum += v
+	hist.count++
+}
+
+func (h *HistogramVec) WriteTo(w io.Writer) (int64, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var b strings.Builder
+	writeHeader(&b, h.name, h.help, "histogram")
+	keys := make([]string, 0, len(h.values))
+	for key := range h.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		hist := h.values[key]
+		for i, upper := range h.buckets {
+			fmt.Fprintf(&b, "%s_bucket%s %d\n", h.name, withLabel(key, "le", formatFloat(upper)), hist.counts[i])
+		}
+		fmt.Fprintf(&b, "%s_bucket%s %d\n", h.name, withLabel(key, "le", "+Inf"), hist.count)
+		fmt.Fprintf(&b, "%s_sum%s %s\n", h.name, key, formatFloat(hist.sum))
+		fmt.Fprintf(&b, "%s_count%s %d\n", h.name, key, hist.count)
+	}
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// WriteGauge() writes a single unlabelled value sampled at scrape time.
+func WriteGauge(w io.Writer, name, help string, value float64) error {
+	return writeSingle(w, name, help, "gauge", value)
+}
+
+// WriteCounter() writes a single unlabelled counter whose value is kept
+// elsewhere, such as the totals reported by sql.DB.Stats().
+func WriteCounter(w io.Writer, name, help string, value float64) error {
+	return writeSingle(w, name, help, "counter", value)
+}
+
+func writeSingle(w io.Writer, name, help, kind string, value float64) error {
+	var b strings.Builder
+	writeHeader(&b, name, help, kind)
+	fmt.Fprintf(&b, "%s %s\n", name, formatFloat(value))
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeHeader(b *strings.Builder, name, help, kind string) {
+	fmt.Fprintf(b, "# HELP %s %s\n", name, strings.ReplaceAll(help, "\n", " "))
+	fmt.Fprintf(b, "# TYPE %s %s\n", name, kind)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// labelKey() renders label pairs as {a="x",b="y"}; the rendered form doubles
+// as the map key for each series.
+func labelKey(labels, values []string) string {
+	if len(labels) != len(values) {
+		panic(fmt.Sprintf("metrics: expected %d label values, got %d", len(labels), len(values)))
+	}
+	if len(labels) == 0 {
+		return ""
+	}
+	pairs := make([]string, len(labels))
+	for i := range labels {
+		pairs[i] = labels[i] + `="` + labelEscaper.Replace(values[i]) + `"`
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func withLabel(key, label, value string) string {
+	pair := label + `="` + labelEscaper.Replace(value) + `"`
+	if key == "" {
+		return "{" + pair + "}"
+	}
+	return strings.TrimSuffix(key, "}") + "," + pair + "}"
+}
+
+func sortedKeys(m map[string]float64) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,339 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// lockKey identifies the Postgres advisory lock held while migrating, so two
+// instances migrating the same database at once run one after the other.
+const lockKey int64 = 7_146_293_041
+
+var ErrUnknownVersion = errors.New("unknown migration version")
+
+// Dialect is the SQL flavour of the database being migrated.
+type Dialect int
+
+const (
+	Postgres Dialect = iota
+	SQLite
+)
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+type Status struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+type Migrator struct {
+	DB         *sql.DB
+	Dialect    Dialect
+	migrations []Migration
+}
+
+// New() reads every NNNNNN_name.up.sql / NNNNNN_name.down.sql pair found at
+// the root of fsys.
+func New(db *sql.DB, fsys fs.FS) (*Migrator, error) {
+	files, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int64]*Migration)
+	for _, file := range files {
+		base := strings.TrimSuffix(path.Base(file), ".sql")
+		var direction string
+		switch {
+		case strings.HasSuffix(base, ".up"):
+			direction = "up"
+		case strings.HasSuffix(base, ".down"):
+			direction = "down"
+		default:
+			return nil, fmt.Errorf("migration %s: missing .up or .down suffix", file)
+		}
+		base = strings.TrimSuffix(base, "."+direction)
+
+		prefix, name, found := strings.Cut(base, "_")
+		if !found {
+			return nil, fmt.Errorf("migration %s: missing version prefix", file)
+		}
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: invalid version prefix", file)
+		}
+
+		body, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		}
+		if direction == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s: missing up file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return &Migrator{DB: db, migrations: migrations}, nil
+}
+
+// Up() applies every pending migration in ascending order.
+func (m *Migrator) Up() error {
+	if len(m.migrations) == 0 {
+		return nil
+	}
+	return m.Goto(m.migrations[len(m.migrations)-1].Version)
+}
+
+// Down() rolls back the most recently applied migration.
+func (m *Migrator) Down() error {
+	return m.withLock(func(conn *sql.Conn) error {
+		applied, err := appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			if _, ok := applied[m.migrations[i].Version]; ok {
+				return m.rollback(conn, m.migrations[i])
+			}
+		}
+		return nil
+	})
+}
+
+// Goto() migrates up or down until exactly the migrations with a version
+// less than or equal to version are applied. Goto(0) rolls back everything.
+func (m *Migrator) Goto(version int64) error {
+	if version != 0 && m.find(version) < 0 {
+		return ErrUnknownVersion
+	}
+	return m.withLock(func(conn *sql.Conn) error {
+		applied, err := appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			mig := m.migrations[i]
+			if _, ok := applied[mig.Version]; ok && mig.Version > version {
+				if err := m.rollback(conn, mig); err != nil {
+					return err
+				}
+			}
+		}
+		for _, mig := range m.migrations {
+			if _, ok := applied[mig.Version]; !ok && mig.Version <= version {
+				if err := m.apply(conn, mig); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// Status() reports every known migration and whether it has been applied.
+func (m *Migrator) Status() ([]Status, error) {
+	var statuses []Status
+	err := m.withLock(func(conn *sql.Conn) error {
+		applied, err := appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			appliedAt, ok := applied[mig.Version]
+			statuses = append(statuses, Status{
+				Version:   mig.Version,
+				Name:      mig.Name,
+				Applied:   ok,
+				AppliedAt: appliedAt,
+			})
+		}
+		return nil
+	})
+	return statuses, err
+}
+
+func (m *Migrator) find(version int64) int {
+	for i := range m.migrations {
+		if m.migrations[i].Version == version {
+			return i
+		}
+	}
+	return -1
+}
+
+// withLock() runs fn on a single connection holding the migration advisory
+// lock, creating the schema_migrations table first if needed. SQLite has no
+// advisory locks; its databases belong to a single node, and each migration
+// still runs in its own write transaction.
+func (m *Migrator) withLock(fn func(conn *sql.Conn) error) error {
+	ctx := context.Background()
+	conn, err := m.DB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	table := `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version bigint PRIMARY KEY,
+    applied_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+)`
+	if m.Dialect == SQLite {
+		table = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version integer PRIMARY KEY,
+    applied_at timestamp NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%S+00:00', 'now'))
+)`
+	} else {
+		_, err = conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockKey)
+		if err != nil {
+			return err
+		}
+		defer conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, lockKey)
+	}
+
+	if err := m.adoptGolangMigrate(ctx, conn, table); err != nil {
+		return err
+	}
+	_, err = conn.ExecContext(ctx, table)
+	if err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+// adoptGolangMigrate() converts a schema_migrations table left by
+// golang-migrate, which holds only the current version and a dirty flag,
+// into one row per applied migration. A dirty database has to be repaired
+// with golang-migrate first, as there is no telling which statements of the
+// failed migration ran.
+func (m *Migrator) adoptGolangMigrate(ctx context.Context, conn *sql.Conn, table string) error {
+	detect := `
+SELECT EXISTS (
+    SELECT 1 FROM information_schema.columns
+    WHERE table_schema = current_schema() AND table_name = 'schema_migrations' AND column_name = 'dirty'
+)`
+	if m.Dialect == SQLite {
+		detect = `SELECT EXISTS (SELECT 1 FROM pragma_table_info('schema_migrations') WHERE name = 'dirty')`
+	}
+	var found bool
+	if err := conn.QueryRowContext(ctx, detect).Scan(&found); err != nil {
+		return err
+	}
+	if !found {
+		return nil
+	}
+
+	var version int64
+	var dirty bool
+	err := conn.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("schema_migrations is marked dirty at version %d by golang-migrate: "+
+			"repair the database and run `migrate force` with golang-migrate before using this tool", version)
+	}
+	if version != 0 && m.find(version) < 0 {
+		return fmt.Errorf("golang-migrate schema_migrations is at version %d: %w", version, ErrUnknownVersion)
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `DROP TABLE schema_migrations`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, table); err != nil {
+		return err
+	}
+	for _, mig := range m.migrations {
+		if mig.Version > version {
+			break
+		}
+		_, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version) VALUES ($1)`, mig.Version)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func appliedVersions(conn *sql.Conn) (map[int64]time.Time, error) {
+	rows, err := conn.QueryContext(context.Background(), `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+func (m *Migrator) apply(conn *sql.Conn, mig Migration) error {
+	return inTx(conn, mig, mig.Up, `INSERT INTO schema_migrations (version) VALUES ($1)`)
+}
+
+func (m *Migrator) rollback(conn *sql.Conn, mig Migration) error {
+	if mig.Down == "" {
+		return fmt.Errorf("migration %d_%s: missing down file", mig.Version, mig.Name)
+	}
+	return inTx(conn, mig, mig.Down, `DELETE FROM schema_migrations WHERE version = $1`)
+}
+
+func inTx(conn *sql.Conn, mig Migration, body, record string) error {
+	ctx := context.Background()
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, body); err != nil {
+		return fmt.Errorf("migration %d_%s: %w", mig.Version, mig.Name, err)
+	}
+	if _, err := tx.ExecContext(ctx, record, mig.Version); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
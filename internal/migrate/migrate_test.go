@@ -0,0 +1,78 @@
+package migrate
+
+import (
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	_ "modernc.org/sqlite"
+)
+
+var testMigrations = fstest.MapFS{
+	"000001_create_a.up.sql":   {Data: []byte(`CREATE TABLE a (id integer)`)},
+	"000001_create_a.down.sql": {Data: []byte(`DROP TABLE a`)},
+	"000002_create_b.up.sql":   {Data: []byte(`CREATE TABLE b (id integer)`)},
+	"000002_create_b.down.sql": {Data: []byte(`DROP TABLE b`)},
+	"000003_create_c.up.sql":   {Data: []byte(`CREATE TABLE c (id integer)`)},
+	"000003_create_c.down.sql": {Data: []byte(`DROP TABLE c`)},
+}
+
+// openGolangMigrate() returns a SQLite database migrated to version by
+// golang-migrate, recorded in its schema_migrations layout.
+func openGolangMigrate(t *testing.T, version int64, dirty bool) *Migrator {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	m, err := New(db, testMigrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Dialect = SQLite
+	for _, mig := range m.migrations[:version] {
+		if _, err := db.Exec(mig.Up); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err = db.Exec(`CREATE TABLE schema_migrations (version bigint NOT NULL PRIMARY KEY, dirty boolean NOT NULL)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`INSERT INTO schema_migrations VALUES ($1, $2)`, version, dirty); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestAdoptGolangMigrate(t *testing.T) {
+	m := openGolangMigrate(t, 2, false)
+	if err := m.Up(); err != nil {
+		t.Fatal(err)
+	}
+	statuses, err := m.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range statuses {
+		if !s.Applied {
+			t.Errorf("migration %d not applied", s.Version)
+		}
+	}
+	// Applying only the pending migration means the tables from the
+	// adopted versions were left alone.
+	if _, err := m.DB.Exec(`SELECT * FROM a, b, c`); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAdoptGolangMigrateDirty(t *testing.T) {
+	m := openGolangMigrate(t, 2, true)
+	err := m.Up()
+	if err == nil || !strings.Contains(err.Error(), "dirty") {
+		t.Fatalf("got error %v, want a dirty database error", err)
+	}
+}
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS movie_revisions;
\ No newline at end of file
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS reviews (
+    user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    movie_id bigint NOT NULL REFERENCES movies ON DELETE CASCADE,
+    score integer NOT NULL CHECK (score BETWEEN 1 AND 10),
+    body text NOT NULL DEFAULT '',
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    version integer NOT NULL DEFAULT 1,
+    PRIMARY KEY (user_id, movie_id)
+);
+CREATE INDEX IF NOT EXISTS reviews_movie_id_idx ON reviews (movie_id);
+ALTER TABLE movies ADD COLUMN IF NOT EXISTS average_rating numeric(4, 2) NOT NULL DEFAULT 0;
+ALTER TABLE movies ADD COLUMN IF NOT EXISTS rating_count integer NOT NULL DEFAULT 0;
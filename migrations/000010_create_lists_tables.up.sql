@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS lists (
+    id bigserial PRIMARY KEY,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    name text NOT NULL,
+    visibility text NOT NULL DEFAULT 'private' CHECK (visibility IN ('public', 'private')),
+    is_default bool NOT NULL DEFAULT false,
+    version integer NOT NULL DEFAULT 1,
+    UNIQUE (user_id, name)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS lists_user_id_default_idx ON lists (user_id) WHERE is_default;
+CREATE TABLE IF NOT EXISTS list_items (
+    list_id bigint NOT NULL REFERENCES lists ON DELETE CASCADE,
+    movie_id bigint NOT NULL REFERENCES movies ON DELETE CASCADE,
+    position integer NOT NULL,
+    added_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (list_id, movie_id)
+);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS movies_title_english_idx;
+DROP INDEX IF EXISTS movies_search_vector_idx;
+ALTER TABLE movies DROP COLUMN IF EXISTS search_vector;
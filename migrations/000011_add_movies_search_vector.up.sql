@@ -0,0 +1,4 @@
+ALTER TABLE movies ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (to_tsvector('simple', title)) STORED;
+CREATE INDEX IF NOT EXISTS movies_search_vector_idx ON movies USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS movies_title_english_idx ON movies USING GIN (to_tsvector('english', title));
@@ -0,0 +1,30 @@
+// Package migrations embeds the SQL migration files so they ship inside the
+// api binary.
+package migrations
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+)
+
+// FS holds the PostgreSQL migrations.
+//
+//go:embed *.sql
+var FS embed.FS
+
+//go:embed sqlite/*.sql
+var sqliteFS embed.FS
+
+// ForDriver() returns the migrations written for the named database driver
+// ("postgres" or "sqlite"). The SQLite files mirror the PostgreSQL ones
+// version for version.
+func ForDriver(driver string) (fs.FS, error) {
+	switch driver {
+	case "postgres":
+		return FS, nil
+	case "sqlite":
+		return fs.Sub(sqliteFS, "sqlite")
+	}
+	return nil, fmt.Errorf("no migrations for database driver %q", driver)
+}
@@ -0,0 +1,10 @@
+-- genres holds a JSON array of strings in place of text[].
+CREATE TABLE IF NOT EXISTS movies (
+id integer PRIMARY KEY AUTOINCREMENT,
+created_at timestamp NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%S+00:00', 'now')),
+title text NOT NULL,
+year integer NOT NULL,
+runtime integer NOT NULL,
+genres text NOT NULL,
+version integer NOT NULL DEFAULT 1
+);
@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS movies_check_update;
+DROP TRIGGER IF EXISTS movies_check_insert;
@@ -0,0 +1,16 @@
+-- SQLite can't add constraints to an existing table, so the checks are
+-- enforced by triggers instead.
+CREATE TRIGGER IF NOT EXISTS movies_check_insert BEFORE INSERT ON movies
+WHEN NEW.runtime < 0
+    OR NEW.year NOT BETWEEN 1888 AND CAST(strftime('%Y', 'now') AS integer)
+    OR json_array_length(NEW.genres) NOT BETWEEN 1 AND 5
+BEGIN
+    SELECT RAISE(ABORT, 'CHECK constraint failed: movies');
+END;
+CREATE TRIGGER IF NOT EXISTS movies_check_update BEFORE UPDATE OF runtime, year, genres ON movies
+WHEN NEW.runtime < 0
+    OR NEW.year NOT BETWEEN 1888 AND CAST(strftime('%Y', 'now') AS integer)
+    OR json_array_length(NEW.genres) NOT BETWEEN 1 AND 5
+BEGIN
+    SELECT RAISE(ABORT, 'CHECK constraint failed: movies');
+END;
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS permissions (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    code text NOT NULL UNIQUE
+);
+CREATE TABLE IF NOT EXISTS users_permissions (
+    user_id integer NOT NULL REFERENCES users ON DELETE CASCADE,
+    permission_id integer NOT NULL REFERENCES permissions ON DELETE CASCADE,
+    PRIMARY KEY (user_id, permission_id)
+);
+INSERT INTO permissions (code)
+VALUES
+    ('movies:read'),
+    ('movies:write');
+-- Accounts created before permissions existed could read movies; keep that.
+INSERT INTO users_permissions
+SELECT users.id, permissions.id FROM users, permissions
+WHERE permissions.code = 'movies:read'
+ON CONFLICT DO NOTHING;
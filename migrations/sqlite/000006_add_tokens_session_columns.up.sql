@@ -0,0 +1,6 @@
+ALTER TABLE tokens ADD COLUMN created_at timestamp NOT NULL DEFAULT '1970-01-01 00:00:00+00:00';
+ALTER TABLE tokens ADD COLUMN user_agent text NOT NULL DEFAULT '';
+ALTER TABLE tokens ADD COLUMN ip text NOT NULL DEFAULT '';
+-- Added columns can't default to the current time, so existing tokens are
+-- stamped here. New tokens always set created_at.
+UPDATE tokens SET created_at = strftime('%Y-%m-%d %H:%M:%S+00:00', 'now');
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS movie_revisions (
+    movie_id integer NOT NULL REFERENCES movies ON DELETE CASCADE,
+    version integer NOT NULL,
+    title text NOT NULL,
+    year integer NOT NULL,
+    runtime integer NOT NULL,
+    genres text NOT NULL,
+    edited_by integer REFERENCES users ON DELETE SET NULL,
+    replaced_at timestamp NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%S+00:00', 'now')),
+    PRIMARY KEY (movie_id, version)
+);
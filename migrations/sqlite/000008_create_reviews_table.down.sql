@@ -0,0 +1,3 @@
+ALTER TABLE movies DROP COLUMN rating_count;
+ALTER TABLE movies DROP COLUMN average_rating;
+DROP TABLE IF EXISTS reviews;
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS reviews (
+    user_id integer NOT NULL REFERENCES users ON DELETE CASCADE,
+    movie_id integer NOT NULL REFERENCES movies ON DELETE CASCADE,
+    score integer NOT NULL CHECK (score BETWEEN 1 AND 10),
+    body text NOT NULL DEFAULT '',
+    created_at timestamp NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%S+00:00', 'now')),
+    version integer NOT NULL DEFAULT 1,
+    PRIMARY KEY (user_id, movie_id)
+);
+CREATE INDEX IF NOT EXISTS reviews_movie_id_idx ON reviews (movie_id);
+ALTER TABLE movies ADD COLUMN average_rating real NOT NULL DEFAULT 0;
+ALTER TABLE movies ADD COLUMN rating_count integer NOT NULL DEFAULT 0;
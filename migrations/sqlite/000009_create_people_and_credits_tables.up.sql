@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS people (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at timestamp NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%S+00:00', 'now')),
+    name text NOT NULL,
+    birth_year integer,
+    bio text NOT NULL DEFAULT '',
+    version integer NOT NULL DEFAULT 1
+);
+CREATE TABLE IF NOT EXISTS credits (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    movie_id integer NOT NULL REFERENCES movies ON DELETE CASCADE,
+    person_id integer NOT NULL REFERENCES people ON DELETE CASCADE,
+    role text NOT NULL CHECK (role IN ('director', 'actor', 'writer')),
+    character text NOT NULL DEFAULT '',
+    UNIQUE (movie_id, person_id, role, character)
+);
+CREATE INDEX IF NOT EXISTS credits_person_id_idx ON credits (person_id);
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS lists (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at timestamp NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%S+00:00', 'now')),
+    user_id integer NOT NULL REFERENCES users ON DELETE CASCADE,
+    name text NOT NULL,
+    visibility text NOT NULL DEFAULT 'private' CHECK (visibility IN ('public', 'private')),
+    is_default boolean NOT NULL DEFAULT false,
+    version integer NOT NULL DEFAULT 1,
+    UNIQUE (user_id, name)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS lists_user_id_default_idx ON lists (user_id) WHERE is_default;
+CREATE TABLE IF NOT EXISTS list_items (
+    list_id integer NOT NULL REFERENCES lists ON DELETE CASCADE,
+    movie_id integer NOT NULL REFERENCES movies ON DELETE CASCADE,
+    position integer NOT NULL,
+    added_at timestamp NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%S+00:00', 'now')),
+    PRIMARY KEY (list_id, movie_id)
+);
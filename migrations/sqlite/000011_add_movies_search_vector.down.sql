@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS movies_fts_update;
+DROP TRIGGER IF EXISTS movies_fts_delete;
+DROP TRIGGER IF EXISTS movies_fts_insert;
+DROP TABLE IF EXISTS movies_fts_english;
+DROP TABLE IF EXISTS movies_fts;
@@ -0,0 +1,26 @@
+-- Titles are indexed by FTS5 instead of tsvector columns: movies_fts
+-- matches whole words like the simple configuration and movies_fts_english
+-- stems them with the porter tokenizer. Both read the titles from movies
+-- and are kept in sync by triggers.
+CREATE VIRTUAL TABLE IF NOT EXISTS movies_fts USING fts5(
+    title, content='movies', content_rowid='id'
+);
+CREATE VIRTUAL TABLE IF NOT EXISTS movies_fts_english USING fts5(
+    title, content='movies', content_rowid='id', tokenize='porter unicode61'
+);
+CREATE TRIGGER IF NOT EXISTS movies_fts_insert AFTER INSERT ON movies BEGIN
+    INSERT INTO movies_fts (rowid, title) VALUES (NEW.id, NEW.title);
+    INSERT INTO movies_fts_english (rowid, title) VALUES (NEW.id, NEW.title);
+END;
+CREATE TRIGGER IF NOT EXISTS movies_fts_delete AFTER DELETE ON movies BEGIN
+    INSERT INTO movies_fts (movies_fts, rowid, title) VALUES ('delete', OLD.id, OLD.title);
+    INSERT INTO movies_fts_english (movies_fts_english, rowid, title) VALUES ('delete', OLD.id, OLD.title);
+END;
+CREATE TRIGGER IF NOT EXISTS movies_fts_update AFTER UPDATE OF title ON movies BEGIN
+    INSERT INTO movies_fts (movies_fts, rowid, title) VALUES ('delete', OLD.id, OLD.title);
+    INSERT INTO movies_fts_english (movies_fts_english, rowid, title) VALUES ('delete', OLD.id, OLD.title);
+    INSERT INTO movies_fts (rowid, title) VALUES (NEW.id, NEW.title);
+    INSERT INTO movies_fts_english (rowid, title) VALUES (NEW.id, NEW.title);
+END;
+INSERT INTO movies_fts (movies_fts) VALUES ('rebuild');
+INSERT INTO movies_fts_english (movies_fts_english) VALUES ('rebuild');
@@ -0,0 +1,2 @@
+-- pg_trgm has no SQLite counterpart. Suggestions call the word_similarity()
+-- function registered by the data package, which needs no index.
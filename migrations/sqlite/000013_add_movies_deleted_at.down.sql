@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS movies_deleted_at_idx;
+ALTER TABLE movies DROP COLUMN deleted_at;
@@ -0,0 +1,2 @@
+ALTER TABLE movies ADD COLUMN deleted_at timestamp;
+CREATE INDEX IF NOT EXISTS movies_deleted_at_idx ON movies (deleted_at) WHERE deleted_at IS NOT NULL;